@@ -0,0 +1,5 @@
+// Package forwardv1 is the gRPC API messages are forwarded with when SINK_TYPE is grpc, see forward.proto.
+// Services receiving the messages implement ForwarderServer.
+package forwardv1
+
+//go:generate protoc -I ../../.. --go_out=../../.. --go_opt=paths=source_relative --go-grpc_out=../../.. --go-grpc_opt=paths=source_relative api/forward/v1/forward.proto
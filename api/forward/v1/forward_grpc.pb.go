@@ -0,0 +1,133 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.28.3
+// source: api/forward/v1/forward.proto
+
+package forwardv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Forwarder_Forward_FullMethodName = "/anyker.forward.v1.Forwarder/Forward"
+)
+
+// ForwarderClient is the client API for Forwarder service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Forwarder is implemented by the services receiving the messages forwarded by anyker over gRPC.
+type ForwarderClient interface {
+	// Forward receives a message. The message is committed once Forward returns successfully,
+	// otherwise it is retried when the status code is UNAVAILABLE, RESOURCE_EXHAUSTED, ABORTED
+	// or DEADLINE_EXCEEDED, then published to the dead letter topic if any.
+	// The deadline of the call is the one of the forward, see GRPC_TIMEOUT.
+	Forward(ctx context.Context, in *ForwardRequest, opts ...grpc.CallOption) (*ForwardResponse, error)
+}
+
+type forwarderClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewForwarderClient(cc grpc.ClientConnInterface) ForwarderClient {
+	return &forwarderClient{cc}
+}
+
+func (c *forwarderClient) Forward(ctx context.Context, in *ForwardRequest, opts ...grpc.CallOption) (*ForwardResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ForwardResponse)
+	err := c.cc.Invoke(ctx, Forwarder_Forward_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ForwarderServer is the server API for Forwarder service.
+// All implementations must embed UnimplementedForwarderServer
+// for forward compatibility.
+//
+// Forwarder is implemented by the services receiving the messages forwarded by anyker over gRPC.
+type ForwarderServer interface {
+	// Forward receives a message. The message is committed once Forward returns successfully,
+	// otherwise it is retried when the status code is UNAVAILABLE, RESOURCE_EXHAUSTED, ABORTED
+	// or DEADLINE_EXCEEDED, then published to the dead letter topic if any.
+	// The deadline of the call is the one of the forward, see GRPC_TIMEOUT.
+	Forward(context.Context, *ForwardRequest) (*ForwardResponse, error)
+	mustEmbedUnimplementedForwarderServer()
+}
+
+// UnimplementedForwarderServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedForwarderServer struct{}
+
+func (UnimplementedForwarderServer) Forward(context.Context, *ForwardRequest) (*ForwardResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Forward not implemented")
+}
+func (UnimplementedForwarderServer) mustEmbedUnimplementedForwarderServer() {}
+func (UnimplementedForwarderServer) testEmbeddedByValue()                   {}
+
+// UnsafeForwarderServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ForwarderServer will
+// result in compilation errors.
+type UnsafeForwarderServer interface {
+	mustEmbedUnimplementedForwarderServer()
+}
+
+func RegisterForwarderServer(s grpc.ServiceRegistrar, srv ForwarderServer) {
+	// If the following call pancis, it indicates UnimplementedForwarderServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Forwarder_ServiceDesc, srv)
+}
+
+func _Forwarder_Forward_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ForwardRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ForwarderServer).Forward(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Forwarder_Forward_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ForwarderServer).Forward(ctx, req.(*ForwardRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Forwarder_ServiceDesc is the grpc.ServiceDesc for Forwarder service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Forwarder_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "anyker.forward.v1.Forwarder",
+	HandlerType: (*ForwarderServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Forward",
+			Handler:    _Forwarder_Forward_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "api/forward/v1/forward.proto",
+}
@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"anyker/config"
+	"anyker/internal/application/correlation"
 	"anyker/internal/domain"
 	"context"
 	"github.com/rs/zerolog/log"
@@ -9,9 +11,10 @@ import (
 	"syscall"
 )
 
-// Run starts the worker, which consumes messages from Kafka and forwards them.
+// Run starts the worker, which consumes messages from the source and forwards them using a pool of workers.
+// Messages without correlation ID are given one generated by generateID.
 // It also handles graceful shutdown on SIGINT or SIGTERM signals.
-func Run(usecase domain.MessageUseCase) {
+func Run(cfg config.Config, usecase domain.MessageUseCase, generateID correlation.Generator) {
 	// consume messages
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -32,15 +35,37 @@ func Run(usecase domain.MessageUseCase) {
 			log.Error().Err(err).Msg("failed to consume messages")
 		}
 	}()
-	log.Info().Msg("Worker listening to Kafka...")
+	log.Info().Msgf("Worker listening to %s...", sourceName(cfg.Source()))
 
 	defer usecase.Close()
 
+	// the use case reports the health of the repositories that support it
+	checker, _ := usecase.(domain.HealthChecker)
+	stopAdminServer := startAdminServer(cfg.AdminAddr, checker)
+	defer stopAdminServer()
+
 	// forward messages
+	pool := newWorkerPool(usecase, cfg.WorkerCount, generateID)
+	pool.start(ctx)
 	for message := range messages {
-		if err := usecase.Forward(ctx, *message); err != nil {
-			log.Error().Err(err).Msg("failed to forward message")
-		}
+		pool.dispatch(message)
 	}
+	pool.stop()
 	log.Info().Msg("Worker stopped.")
 }
+
+// sourceName returns the name of a source for the logs, see config.Config.Source.
+func sourceName(source string) string {
+	switch source {
+	case config.SourceKafka:
+		return "Kafka"
+	case config.SourceAMQP:
+		return "AMQP"
+	case config.SourceNATS:
+		return "NATS"
+	case config.SourceRedis:
+		return "Redis"
+	default:
+		return source
+	}
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"anyker/internal/application/correlation"
+	"anyker/internal/domain"
+	"context"
+	"github.com/rs/zerolog/log"
+	"hash/fnv"
+	"sync"
+)
+
+// workerQueueSize is the number of messages that can wait for each worker.
+const workerQueueSize = 8
+
+// workerPool forwards messages concurrently.
+// Messages sharing the same key are always handled by the same worker, so they are forwarded in the order they were consumed.
+type workerPool struct {
+	usecase    domain.MessageUseCase
+	generateID correlation.Generator
+	queues     []chan *domain.Message
+	next       int // worker for the next message without key
+	wg         sync.WaitGroup
+}
+
+// newWorkerPool creates a pool with the given number of workers, at least one.
+// Messages without correlation ID are given one by generateID, if not nil.
+func newWorkerPool(usecase domain.MessageUseCase, size int, generateID correlation.Generator) *workerPool {
+	if size < 1 {
+		size = 1
+	}
+	queues := make([]chan *domain.Message, size)
+	for i := range queues {
+		queues[i] = make(chan *domain.Message, workerQueueSize)
+	}
+	return &workerPool{
+		usecase:    usecase,
+		generateID: generateID,
+		queues:     queues,
+	}
+}
+
+// start starts the workers.
+func (p *workerPool) start(ctx context.Context) {
+	for _, queue := range p.queues {
+		p.wg.Add(1)
+		go func(queue <-chan *domain.Message) {
+			defer p.wg.Done()
+			for message := range queue {
+				p.process(ctx, message)
+			}
+		}(queue)
+	}
+}
+
+// dispatch queues a message on the worker assigned to its key.
+// Messages without key are spread across the workers.
+func (p *workerPool) dispatch(message *domain.Message) {
+	var worker int
+	if message.Key == "" {
+		worker = p.next
+		p.next = (p.next + 1) % len(p.queues)
+	} else {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(message.Key))
+		worker = int(h.Sum32() % uint32(len(p.queues)))
+	}
+	p.queues[worker] <- message
+}
+
+// stop waits for the workers to process the queued messages and stops them.
+func (p *workerPool) stop() {
+	for _, queue := range p.queues {
+		close(queue)
+	}
+	p.wg.Wait()
+}
+
+// process forwards a message, committing it only after it has been forwarded,
+// so no message is lost if the worker stops in the middle of a forward. A message that could not be forwarded
+// is rejected when the use case supports it, unless the forward was interrupted by a shutdown:
+// the message is then left unacked, so the source delivers it again.
+// The message is logged with its correlation ID, by the logger of the context.
+func (p *workerPool) process(ctx context.Context, message *domain.Message) {
+	if p.generateID != nil {
+		correlation.Ensure(message, p.generateID)
+	}
+	ctx = correlation.WithLogger(ctx, *message)
+
+	if err := p.usecase.Forward(ctx, *message); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("failed to forward message")
+		if ctx.Err() != nil {
+			// a forward interrupted by a shutdown is not a permanent failure
+			return
+		}
+		if rejecter, ok := p.usecase.(domain.Rejecter); ok {
+			if err := rejecter.Reject(ctx, *message); err != nil {
+				log.Ctx(ctx).Error().Err(err).Msg("failed to reject message")
+			}
+		}
+		return
+	}
+	if err := p.usecase.Commit(ctx, *message); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("failed to commit message")
+	}
+}
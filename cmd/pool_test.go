@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"anyker/internal/domain"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeUsecase records the forwarded, committed and rejected messages.
+type fakeUsecase struct {
+	mu        sync.Mutex
+	forwarded map[string][]int64
+	committed []int64
+	rejected  []int64
+	failKey   string
+	// correlationIDs are the correlation IDs of the forwarded messages by key
+	correlationIDs map[string]string
+}
+
+func newFakeUsecase() *fakeUsecase {
+	return &fakeUsecase{forwarded: make(map[string][]int64), correlationIDs: make(map[string]string)}
+}
+
+func (f *fakeUsecase) Forward(_ context.Context, message domain.Message) error {
+	// give other workers a chance to run, so ordering issues would show up
+	time.Sleep(time.Millisecond)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.forwarded[message.Key] = append(f.forwarded[message.Key], message.Offset)
+	f.correlationIDs[message.Key] = message.CorrelationID
+	if message.Key == f.failKey {
+		return errors.New("forward error")
+	}
+	return nil
+}
+
+func (f *fakeUsecase) Commit(_ context.Context, message domain.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.committed = append(f.committed, message.Offset)
+	return nil
+}
+
+func (f *fakeUsecase) Reject(_ context.Context, message domain.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rejected = append(f.rejected, message.Offset)
+	return nil
+}
+
+func (f *fakeUsecase) Consume(context.Context, chan<- *domain.Message) error {
+	return nil
+}
+
+func (f *fakeUsecase) Close() error {
+	return nil
+}
+
+func TestWorkerPool(t *testing.T) {
+	t.Run("messages with the same key are forwarded in order", func(t *testing.T) {
+		usecase := newFakeUsecase()
+		pool := newWorkerPool(usecase, 4, nil)
+		pool.start(context.Background())
+
+		for offset := int64(0); offset < 100; offset++ {
+			pool.dispatch(&domain.Message{Key: fmt.Sprintf("origin:%d", offset%5), Offset: offset})
+		}
+		pool.stop()
+
+		assert.Len(t, usecase.forwarded, 5)
+		for key, offsets := range usecase.forwarded {
+			assert.Len(t, offsets, 20, key)
+			assert.IsIncreasing(t, offsets, key)
+		}
+		assert.Len(t, usecase.committed, 100)
+	})
+
+	t.Run("messages without key are spread across workers", func(t *testing.T) {
+		usecase := newFakeUsecase()
+		pool := newWorkerPool(usecase, 3, nil)
+
+		for offset := int64(0); offset < 6; offset++ {
+			pool.dispatch(&domain.Message{Offset: offset})
+		}
+		for _, queue := range pool.queues {
+			assert.Len(t, queue, 2)
+		}
+
+		pool.start(context.Background())
+		pool.stop()
+		assert.Len(t, usecase.forwarded[""], 6)
+	})
+
+	t.Run("failed messages are rejected, not committed", func(t *testing.T) {
+		usecase := newFakeUsecase()
+		usecase.failKey = "origin:fail"
+		pool := newWorkerPool(usecase, 2, nil)
+		pool.start(context.Background())
+
+		pool.dispatch(&domain.Message{Key: "origin:ok", Offset: 1})
+		pool.dispatch(&domain.Message{Key: "origin:fail", Offset: 2})
+		pool.stop()
+
+		assert.Equal(t, []int64{1}, usecase.committed)
+		assert.Equal(t, []int64{2}, usecase.rejected)
+	})
+
+	t.Run("messages interrupted by a shutdown are not rejected", func(t *testing.T) {
+		usecase := newFakeUsecase()
+		usecase.failKey = "origin:fail"
+		pool := newWorkerPool(usecase, 1, nil)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		pool.start(ctx)
+
+		pool.dispatch(&domain.Message{Key: "origin:fail", Offset: 1})
+		pool.stop()
+
+		assert.Equal(t, []int64{1}, usecase.forwarded["origin:fail"])
+		assert.Empty(t, usecase.rejected)
+		assert.Empty(t, usecase.committed)
+	})
+
+	t.Run("messages without correlation ID are given one", func(t *testing.T) {
+		usecase := newFakeUsecase()
+		pool := newWorkerPool(usecase, 1, func() string { return "generated" })
+		pool.start(context.Background())
+
+		pool.dispatch(&domain.Message{Key: "origin:a", Headers: map[string]string{"correlation_id": "abc"}})
+		pool.dispatch(&domain.Message{Key: "origin:b"})
+		pool.stop()
+
+		assert.Equal(t, map[string]string{"origin:a": "abc", "origin:b": "generated"}, usecase.correlationIDs)
+	})
+
+	t.Run("at least one worker", func(t *testing.T) {
+		pool := newWorkerPool(newFakeUsecase(), 0, nil)
+
+		assert.Len(t, pool.queues, 1)
+	})
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"anyker/internal/domain"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"time"
+)
+
+// adminShutdownTimeout is how long to wait for in-flight admin requests on shutdown.
+const adminShutdownTimeout = 5 * time.Second
+
+// newAdminServer creates the HTTP server exposing the Prometheus metrics on /metrics,
+// and the liveness and readiness of the checker on /healthz and /readyz. A nil checker is always live and ready.
+func newAdminServer(addr string, checker domain.HealthChecker) *http.Server {
+	live, ready := func() error { return nil }, func() error { return nil }
+	if checker != nil {
+		live, ready = checker.Live, checker.Ready
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", healthHandler(live))
+	mux.HandleFunc("/readyz", healthHandler(ready))
+
+	return &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+}
+
+// healthHandler responds 200 when the check passes and 503 with the reason otherwise.
+func healthHandler(check func() error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if err := check(); err != nil {
+			log.Debug().Err(err).Msgf("%s check failed", r.URL.Path)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintln(w, err)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	}
+}
+
+// startAdminServer starts the admin server in the background and returns a function that shuts it down.
+func startAdminServer(addr string, checker domain.HealthChecker) (stop func()) {
+	server := newAdminServer(addr, checker)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Err(err).Msgf("admin server failed on %s", addr)
+		}
+	}()
+	log.Info().Msgf("Admin server listening on %s", addr)
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), adminShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			log.Error().Err(err).Msg("failed to shut down admin server")
+		}
+	}
+}
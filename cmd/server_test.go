@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"anyker/internal/domain"
+	"anyker/internal/metrics"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminServer_Metrics(t *testing.T) {
+	metrics.MessagesConsumed.WithLabelValues("admin-test-topic").Inc()
+	server := newAdminServer(":0", nil)
+
+	recorder := httptest.NewRecorder()
+	server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	body, err := io.ReadAll(recorder.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `anyker_messages_consumed_total{topic="admin-test-topic"}`)
+	assert.Contains(t, string(body), "anyker_forward_duration_seconds")
+}
+
+// fakeHealthChecker is a domain.HealthChecker returning fixed errors.
+type fakeHealthChecker struct {
+	live  error
+	ready error
+}
+
+func (f fakeHealthChecker) Live() error {
+	return f.live
+}
+
+func (f fakeHealthChecker) Ready() error {
+	return f.ready
+}
+
+func TestAdminServer_Health(t *testing.T) {
+	tests := []struct {
+		name           string
+		checker        domain.HealthChecker
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "no checker live", path: "/healthz", expectedStatus: http.StatusOK, expectedBody: "ok\n"},
+		{name: "no checker ready", path: "/readyz", expectedStatus: http.StatusOK, expectedBody: "ok\n"},
+		{
+			name:           "live",
+			checker:        fakeHealthChecker{ready: errors.New("kafka consumer has no partition assigned")},
+			path:           "/healthz",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "ok\n",
+		},
+		{
+			name:           "not ready",
+			checker:        fakeHealthChecker{ready: errors.New("kafka consumer has no partition assigned")},
+			path:           "/readyz",
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   "kafka consumer has no partition assigned\n",
+		},
+		{
+			name:           "not live",
+			checker:        fakeHealthChecker{live: errors.New("kafka consumer has not polled for 5m0s")},
+			path:           "/healthz",
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   "kafka consumer has not polled for 5m0s\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newAdminServer(":0", tt.checker)
+
+			recorder := httptest.NewRecorder()
+			server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			assert.Equal(t, tt.expectedBody, recorder.Body.String())
+		})
+	}
+}
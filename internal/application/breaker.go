@@ -0,0 +1,247 @@
+package application
+
+import (
+	"anyker/config"
+	"anyker/internal/domain"
+	"anyker/internal/metrics"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of a CircuitBreaker.
+type CircuitState int
+
+const (
+	// CircuitClosed forwards every message.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen holds the messages until the cooldown has elapsed.
+	CircuitOpen
+	// CircuitHalfOpen forwards one trial message at a time to find out whether the downstream service recovered.
+	CircuitHalfOpen
+)
+
+// String returns the name of the state.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("CircuitState(%d)", int(s))
+	}
+}
+
+// CircuitBreaker is a domain.ForwardRepository that stops forwarding while the downstream service is failing.
+// After the configured number of consecutive failures it opens: consumption is paused and the messages being
+// forwarded wait, instead of failing one by one, until the cooldown elapses. It is then half-open and forwards
+// trial messages one at a time, closing again after the configured number of successes or opening on a failure.
+type CircuitBreaker struct {
+	forwardRepository domain.ForwardRepository
+	pauser            domain.Pauser
+	failureThreshold  int
+	successThreshold  int
+	cooldown          time.Duration
+
+	mu        sync.Mutex
+	state     CircuitState
+	failures  int
+	successes int
+	openedAt  time.Time
+	// trial is set while a half-open trial forward is in flight
+	trial bool
+	// changed is closed and replaced on every state change, to wake up the forwards waiting for it
+	changed chan struct{}
+}
+
+// NewCircuitBreaker creates a new CircuitBreaker around the forward repository.
+// The pauser is optional, when nil consumption is not paused while the circuit is open.
+func NewCircuitBreaker(
+	config config.Config,
+	forwardRepository domain.ForwardRepository,
+	pauser domain.Pauser) domain.ForwardRepository {
+	successThreshold := config.CircuitBreakerSuccessThreshold
+	if successThreshold < 1 {
+		successThreshold = 1
+	}
+	return &CircuitBreaker{
+		forwardRepository: forwardRepository,
+		pauser:            pauser,
+		failureThreshold:  config.CircuitBreakerFailureThreshold,
+		successThreshold:  successThreshold,
+		cooldown:          config.CircuitBreakerCooldown,
+		changed:           make(chan struct{}),
+	}
+}
+
+// Forward forwards a message when the circuit is closed, or as a trial when it is half-open.
+// Otherwise it waits until the message can be forwarded, or returns the context error if it is done first.
+func (b *CircuitBreaker) Forward(ctx context.Context, message domain.Message) error {
+	trial, err := b.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	err = b.forwardRepository.Forward(ctx, message)
+	b.record(trial, err)
+	return err
+}
+
+// State returns the current state of the circuit.
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Live returns an error when the wrapped forward repository is not live.
+func (b *CircuitBreaker) Live() error {
+	return checkLive(b.forwardRepository)
+}
+
+// Ready returns an error when the circuit is not closed or the wrapped forward repository is not ready.
+func (b *CircuitBreaker) Ready() error {
+	if state := b.State(); state != CircuitClosed {
+		return fmt.Errorf("circuit breaker is %s", state)
+	}
+	return checkReady(b.forwardRepository)
+}
+
+// acquire waits until a message can be forwarded, and reports whether it is a half-open trial.
+func (b *CircuitBreaker) acquire(ctx context.Context) (trial bool, err error) {
+	for {
+		b.mu.Lock()
+		if b.state == CircuitOpen && time.Since(b.openedAt) >= b.cooldown {
+			b.setState(CircuitHalfOpen)
+		}
+		switch {
+		case b.state == CircuitClosed:
+			b.mu.Unlock()
+			return false, nil
+		case b.state == CircuitHalfOpen && !b.trial:
+			b.trial = true
+			b.mu.Unlock()
+			return true, nil
+		}
+		// open until the cooldown elapses, or half-open with a trial in flight
+		changed := b.changed
+		wait := time.Duration(-1)
+		if b.state == CircuitOpen {
+			wait = b.cooldown - time.Since(b.openedAt)
+		}
+		b.mu.Unlock()
+
+		if err := b.wait(ctx, changed, wait); err != nil {
+			return false, err
+		}
+	}
+}
+
+// wait waits until the circuit changes, the given duration elapses if not negative, or the context is done.
+func (b *CircuitBreaker) wait(ctx context.Context, changed <-chan struct{}, d time.Duration) error {
+	var elapsed <-chan time.Time
+	if d >= 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		elapsed = timer.C
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-changed:
+		return nil
+	case <-elapsed:
+		return nil
+	}
+}
+
+// record updates the state of the circuit with the outcome of a forward.
+func (b *CircuitBreaker) record(trial bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if trial {
+		b.trial = false
+	}
+	if err != nil && !countsAsFailure(err) {
+		// says nothing about the downstream service, let another trial go
+		if trial {
+			b.notify()
+		}
+		return
+	}
+
+	if err != nil {
+		b.successes = 0
+		b.failures++
+		if trial || (b.state == CircuitClosed && b.failures >= b.failureThreshold) {
+			log.Warn().Err(err).Int("failures", b.failures).Dur("cooldown", b.cooldown).Msg("circuit breaker opened")
+			b.openedAt = time.Now()
+			b.setState(CircuitOpen)
+		}
+		return
+	}
+
+	b.failures = 0
+	if !trial {
+		return
+	}
+	b.successes++
+	if b.successes >= b.successThreshold {
+		log.Info().Msg("circuit breaker closed")
+		b.successes = 0
+		b.setState(CircuitClosed)
+		return
+	}
+	b.notify()
+}
+
+// setState changes the state of the circuit, pausing consumption while it is not closed.
+// It must be called with the lock held.
+func (b *CircuitBreaker) setState(state CircuitState) {
+	if b.state == state {
+		return
+	}
+	wasClosed := b.state == CircuitClosed
+	b.state = state
+	metrics.CircuitBreakerState.Set(float64(state))
+	if b.pauser != nil && wasClosed != (state == CircuitClosed) {
+		if state == CircuitClosed {
+			b.pauser.Resume()
+		} else {
+			b.pauser.Pause()
+		}
+	}
+	b.notify()
+}
+
+// notify wakes up the forwards waiting for the circuit. It must be called with the lock held.
+func (b *CircuitBreaker) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// countsAsFailure reports whether a forward error means the downstream service is unavailable.
+// Client errors mean the service is up, and messages without route or interrupted by a shutdown never reached it.
+func countsAsFailure(err error) bool {
+	if errors.Is(err, domain.ErrNoRoute) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var forwardErr *domain.ForwardError
+	if errors.As(err, &forwardErr) {
+		if forwardErr.ClientError {
+			return false
+		}
+		if forwardErr.StatusCode != 0 {
+			return forwardErr.StatusCode >= 500 || forwardErr.StatusCode == http.StatusTooManyRequests
+		}
+	}
+	return true
+}
@@ -0,0 +1,159 @@
+package application
+
+import (
+	"anyker/config"
+	"anyker/internal/domain"
+	"anyker/internal/domain/mocks"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCircuitBreaker_Forward(t *testing.T) {
+	cfg := config.Config{
+		CircuitBreakerFailureThreshold: 2,
+		CircuitBreakerSuccessThreshold: 1,
+		CircuitBreakerCooldown:         20 * time.Millisecond,
+	}
+	ctx := context.Background()
+	msg := domain.Message{Key: "telegram:user-1", Content: []byte("hello")}
+	unavailable := &domain.ForwardError{StatusCode: 503, Attempts: 3, Err: errors.New("unexpected status code: 503")}
+
+	t.Run("opens after consecutive failures and closes after a successful trial", func(t *testing.T) {
+		forwardRepo := new(mocks.MockForwardRepository)
+		pauser := new(mocks.MockPauser)
+		breaker := NewCircuitBreaker(cfg, forwardRepo, pauser).(*CircuitBreaker)
+
+		forwardRepo.On("Forward", ctx, msg).Return(unavailable).Twice()
+		pauser.On("Pause").Return().Once()
+
+		assert.ErrorIs(t, breaker.Forward(ctx, msg), unavailable)
+		assert.Equal(t, CircuitClosed, breaker.State())
+		assert.ErrorIs(t, breaker.Forward(ctx, msg), unavailable)
+		assert.Equal(t, CircuitOpen, breaker.State())
+		assert.EqualError(t, breaker.Ready(), "circuit breaker is open")
+
+		// waits for the cooldown, then forwards the message as a trial
+		forwardRepo.On("Forward", ctx, msg).Return(nil).Once()
+		pauser.On("Resume").Return().Once()
+
+		start := time.Now()
+		assert.NoError(t, breaker.Forward(ctx, msg))
+		assert.GreaterOrEqual(t, time.Since(start), 15*time.Millisecond)
+		assert.Equal(t, CircuitClosed, breaker.State())
+		assert.NoError(t, breaker.Ready())
+
+		forwardRepo.AssertExpectations(t)
+		pauser.AssertExpectations(t)
+	})
+
+	t.Run("failed trial opens the circuit again", func(t *testing.T) {
+		forwardRepo := new(mocks.MockForwardRepository)
+		breaker := NewCircuitBreaker(cfg, forwardRepo, nil).(*CircuitBreaker)
+
+		forwardRepo.On("Forward", ctx, msg).Return(unavailable).Times(3)
+
+		assert.Error(t, breaker.Forward(ctx, msg))
+		assert.Error(t, breaker.Forward(ctx, msg))
+		assert.Equal(t, CircuitOpen, breaker.State())
+
+		assert.Error(t, breaker.Forward(ctx, msg))
+		assert.Equal(t, CircuitOpen, breaker.State())
+		forwardRepo.AssertExpectations(t)
+	})
+
+	t.Run("client errors do not open the circuit", func(t *testing.T) {
+		forwardRepo := new(mocks.MockForwardRepository)
+		breaker := NewCircuitBreaker(cfg, forwardRepo, nil).(*CircuitBreaker)
+
+		badRequest := &domain.ForwardError{StatusCode: 400, Attempts: 1, Err: errors.New("unexpected status code: 400")}
+		forwardRepo.On("Forward", ctx, msg).Return(badRequest).Times(3)
+
+		for i := 0; i < 3; i++ {
+			assert.ErrorIs(t, breaker.Forward(ctx, msg), badRequest)
+		}
+		assert.Equal(t, CircuitClosed, breaker.State())
+		forwardRepo.AssertExpectations(t)
+	})
+
+	t.Run("waiting forward gives up when the context is done", func(t *testing.T) {
+		forwardRepo := new(mocks.MockForwardRepository)
+		slowCfg := cfg
+		slowCfg.CircuitBreakerCooldown = time.Hour
+		breaker := NewCircuitBreaker(slowCfg, forwardRepo, nil).(*CircuitBreaker)
+
+		forwardRepo.On("Forward", mock.Anything, msg).Return(unavailable).Twice()
+		assert.Error(t, breaker.Forward(ctx, msg))
+		assert.Error(t, breaker.Forward(ctx, msg))
+
+		cancelCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+
+		assert.ErrorIs(t, breaker.Forward(cancelCtx, msg), context.DeadlineExceeded)
+		forwardRepo.AssertExpectations(t)
+	})
+
+	t.Run("one trial at a time while half-open", func(t *testing.T) {
+		forwardRepo := new(mocks.MockForwardRepository)
+		breaker := NewCircuitBreaker(cfg, forwardRepo, nil).(*CircuitBreaker)
+
+		forwardRepo.On("Forward", ctx, msg).Return(unavailable).Twice()
+		assert.Error(t, breaker.Forward(ctx, msg))
+		assert.Error(t, breaker.Forward(ctx, msg))
+
+		trialStarted := make(chan struct{})
+		release := make(chan struct{})
+		forwardRepo.On("Forward", ctx, msg).Run(func(mock.Arguments) {
+			close(trialStarted)
+			<-release
+		}).Return(nil).Once()
+		forwardRepo.On("Forward", ctx, msg).Return(nil).Once()
+
+		trialDone := make(chan error)
+		go func() { trialDone <- breaker.Forward(ctx, msg) }()
+		<-trialStarted
+		assert.Equal(t, CircuitHalfOpen, breaker.State())
+
+		waitingDone := make(chan error)
+		go func() { waitingDone <- breaker.Forward(ctx, msg) }()
+		select {
+		case <-waitingDone:
+			t.Fatal("forward did not wait for the trial")
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		close(release)
+		assert.NoError(t, <-trialDone)
+		assert.NoError(t, <-waitingDone)
+		assert.Equal(t, CircuitClosed, breaker.State())
+		forwardRepo.AssertExpectations(t)
+	})
+}
+
+func TestCountsAsFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "server error", err: &domain.ForwardError{StatusCode: 502}, expected: true},
+		{name: "too many requests", err: &domain.ForwardError{StatusCode: 429}, expected: true},
+		{name: "transport error", err: &domain.ForwardError{Err: errors.New("connection refused")}, expected: true},
+		{name: "other error", err: errors.New("broken"), expected: true},
+		{name: "client error", err: &domain.ForwardError{StatusCode: 404}, expected: false},
+		{name: "client error without status code", err: &domain.ForwardError{ClientError: true}, expected: false},
+		{name: "no route", err: fmt.Errorf("%w: slack", domain.ErrNoRoute), expected: false},
+		{name: "shutdown", err: &domain.ForwardError{Err: context.Canceled}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, countsAsFailure(tt.err))
+		})
+	}
+}
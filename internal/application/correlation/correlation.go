@@ -0,0 +1,65 @@
+// Package correlation assigns correlation IDs to messages, so a message can be traced across services
+// and every log line about it carries the same ID.
+package correlation
+
+import (
+	"anyker/internal/domain"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/oklog/ulid/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// Formats of the generated correlation IDs.
+const (
+	FormatUUID = "uuid"
+	FormatULID = "ulid"
+)
+
+// Generator generates a new correlation ID.
+type Generator func() string
+
+// NewGenerator creates a Generator of IDs in the given format, UUID if empty.
+// ULIDs are sortable by generation time, which helps when browsing logs.
+func NewGenerator(format string) (Generator, error) {
+	switch strings.ToLower(format) {
+	case "", FormatUUID:
+		return func() string { return uuid.NewString() }, nil
+	case FormatULID:
+		return func() string { return ulid.Make().String() }, nil
+	default:
+		return nil, fmt.Errorf("unknown correlation ID format: %s", format)
+	}
+}
+
+// Ensure sets the correlation ID of the message from its correlation_id header,
+// generating a new one when the header is missing or empty.
+func Ensure(message *domain.Message, generate Generator) {
+	if message.CorrelationID != "" {
+		return
+	}
+	if id := message.Headers[domain.CorrelationIDHeader]; id != "" {
+		message.CorrelationID = id
+		return
+	}
+	message.CorrelationID = generate()
+}
+
+// WithLogger returns a copy of ctx with a logger adding the correlation ID and the position of the message
+// to every log line, retrieved with log.Ctx.
+func WithLogger(ctx context.Context, message domain.Message) context.Context {
+	logContext := log.With().
+		Str("correlation_id", message.CorrelationID).
+		Str("key", message.Key).
+		Str("topic", message.Topic).
+		Int32("partition", message.Partition).
+		Int64("offset", message.Offset)
+	if message.ID != "" {
+		logContext = logContext.Str("id", message.ID)
+	}
+	logger := logContext.Logger()
+	return logger.WithContext(ctx)
+}
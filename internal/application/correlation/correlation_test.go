@@ -0,0 +1,94 @@
+package correlation
+
+import (
+	"anyker/internal/domain"
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/oklog/ulid/v2"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewGenerator(t *testing.T) {
+	t.Run("uuid", func(t *testing.T) {
+		for _, format := range []string{"", "uuid", "UUID"} {
+			generate, err := NewGenerator(format)
+			require.NoError(t, err)
+
+			id := generate()
+			_, err = uuid.Parse(id)
+			assert.NoError(t, err, id)
+			assert.NotEqual(t, id, generate())
+		}
+	})
+
+	t.Run("ulid", func(t *testing.T) {
+		generate, err := NewGenerator("ulid")
+		require.NoError(t, err)
+
+		first, second := generate(), generate()
+		_, err = ulid.ParseStrict(first)
+		assert.NoError(t, err, first)
+		assert.Less(t, first, second)
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		_, err := NewGenerator("snowflake")
+		assert.EqualError(t, err, "unknown correlation ID format: snowflake")
+	})
+}
+
+func TestEnsure(t *testing.T) {
+	generate := func() string { return "generated" }
+
+	tests := []struct {
+		name     string
+		message  domain.Message
+		expected string
+	}{
+		{name: "from header", message: domain.Message{Headers: map[string]string{"correlation_id": "abc"}}, expected: "abc"},
+		{name: "missing header", message: domain.Message{}, expected: "generated"},
+		{name: "empty header", message: domain.Message{Headers: map[string]string{"correlation_id": ""}}, expected: "generated"},
+		{name: "already set", message: domain.Message{CorrelationID: "set"}, expected: "set"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Ensure(&tt.message, generate)
+			assert.Equal(t, tt.expected, tt.message.CorrelationID)
+		})
+	}
+}
+
+func TestWithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	previous := log.Logger
+	log.Logger = zerolog.New(&buf)
+	defer func() { log.Logger = previous }()
+
+	ctx := WithLogger(context.Background(), domain.Message{
+		CorrelationID: "abc",
+		Key:           "telegram:123",
+		Topic:         "orders",
+		Partition:     2,
+		Offset:        42,
+	})
+	log.Ctx(ctx).Info().Msg("forwarded")
+
+	assert.JSONEq(t,
+		`{"level":"info","correlation_id":"abc","key":"telegram:123","topic":"orders","partition":2,"offset":42,"message":"forwarded"}`,
+		buf.String())
+
+	buf.Reset()
+	ctx = WithLogger(context.Background(), domain.Message{CorrelationID: "abc", Topic: "orders", ID: "1700000000000-0"})
+	log.Ctx(ctx).Info().Msg("forwarded")
+
+	assert.JSONEq(t,
+		`{"level":"info","correlation_id":"abc","key":"","topic":"orders","partition":0,"offset":0,"id":"1700000000000-0","message":"forwarded"}`,
+		buf.String())
+}
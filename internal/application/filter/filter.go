@@ -0,0 +1,271 @@
+// Package filter evaluates filter expressions over messages, to decide which ones are forwarded.
+//
+// Expressions compare fields of the message with literals, and combine comparisons with &&, || and !:
+//
+//	headers.type == "text" && payload.chat.id != null
+//	key.origin =~ "^whats" || topic == "priority"
+//
+// The available fields are:
+//
+//	headers.<name> or headers["<name>"]   the value of a message header, null if absent
+//	key, key.origin, key.routing_id       the message key and its origin:routingId parts
+//	topic                                 the topic the message was consumed from
+//	payload.<path>                        a value of the JSON content, e.g. payload.items[0].id, null if absent
+//
+// Literals are strings (double or single-quoted), numbers, true, false and null.
+// Comparisons between values of different types are false, =~ matches a regular expression.
+// A field alone is true when it is present and not false, 0 or an empty string.
+package filter
+
+import (
+	"anyker/internal/domain"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strconv"
+)
+
+// Filter is a compiled filter expression.
+type Filter struct {
+	expression string
+	root       node
+}
+
+// Compile parses a filter expression.
+func Compile(expression string) (*Filter, error) {
+	root, err := parse(expression)
+	if err != nil {
+		return nil, err
+	}
+	return &Filter{expression: expression, root: root}, nil
+}
+
+// String returns the source expression of the filter.
+func (f *Filter) String() string {
+	return f.expression
+}
+
+// Match reports whether the message matches the filter expression.
+func (f *Filter) Match(message domain.Message) bool {
+	return truthy(f.root.eval(&env{message: message}))
+}
+
+// env is the message an expression is evaluated against, with its payload decoded on first use.
+type env struct {
+	message domain.Message
+	decoded bool
+	payload interface{}
+}
+
+// getPayload returns the decoded JSON payload, or nil if the content is not valid JSON.
+func (e *env) getPayload() interface{} {
+	if !e.decoded {
+		e.decoded = true
+		if err := json.Unmarshal(e.message.Content, &e.payload); err != nil {
+			e.payload = nil
+		}
+	}
+	return e.payload
+}
+
+// node is a node of the syntax tree of an expression.
+type node interface {
+	eval(e *env) interface{}
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n literalNode) eval(*env) interface{} {
+	return n.value
+}
+
+// validRoots are the message fields that can be referenced.
+var validRoots = map[string]bool{"headers": true, "key": true, "topic": true, "payload": true}
+
+type pathNode struct {
+	root     string
+	segments []string
+}
+
+// validate checks that the path references an existing message field.
+func (n pathNode) validate() error {
+	switch n.root {
+	case "headers":
+		if len(n.segments) != 1 {
+			return errors.New("headers must be followed by a header name")
+		}
+	case "key":
+		if len(n.segments) > 1 || (len(n.segments) == 1 && n.segments[0] != "origin" && n.segments[0] != "routing_id") {
+			return errors.New("key only has the origin and routing_id fields")
+		}
+	case "topic":
+		if len(n.segments) > 0 {
+			return errors.New("topic has no fields")
+		}
+	}
+	return nil
+}
+
+func (n pathNode) eval(e *env) interface{} {
+	switch n.root {
+	case "headers":
+		if value, ok := e.message.Headers[n.segments[0]]; ok {
+			return value
+		}
+		return nil
+	case "key":
+		if len(n.segments) == 0 {
+			return e.message.Key
+		}
+		origin, routingID := domain.ParseKey(e.message.Key)
+		if n.segments[0] == "origin" {
+			return origin
+		}
+		return routingID
+	case "topic":
+		return e.message.Topic
+	default:
+		value := e.getPayload()
+		for _, segment := range n.segments {
+			switch v := value.(type) {
+			case map[string]interface{}:
+				value = v[segment]
+			case []interface{}:
+				index, err := strconv.Atoi(segment)
+				if err != nil || index < 0 || index >= len(v) {
+					return nil
+				}
+				value = v[index]
+			default:
+				return nil
+			}
+		}
+		return value
+	}
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n notNode) eval(e *env) interface{} {
+	return !truthy(n.operand.eval(e))
+}
+
+type andNode struct {
+	left, right node
+}
+
+func (n andNode) eval(e *env) interface{} {
+	return truthy(n.left.eval(e)) && truthy(n.right.eval(e))
+}
+
+type orNode struct {
+	left, right node
+}
+
+func (n orNode) eval(e *env) interface{} {
+	return truthy(n.left.eval(e)) || truthy(n.right.eval(e))
+}
+
+type matchNode struct {
+	operand node
+	re      *regexp.Regexp
+}
+
+func (n matchNode) eval(e *env) interface{} {
+	s, ok := n.operand.eval(e).(string)
+	return ok && n.re.MatchString(s)
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n compareNode) eval(e *env) interface{} {
+	left, right := n.left.eval(e), n.right.eval(e)
+	switch n.op {
+	case "==":
+		return equal(left, right)
+	case "!=":
+		return !equal(left, right)
+	}
+
+	cmp, ok := compare(left, right)
+	if !ok {
+		return false
+	}
+	switch n.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+// equal compares two values, values of different types are not equal.
+func equal(left, right interface{}) bool {
+	switch l := left.(type) {
+	case nil:
+		return right == nil
+	case string, float64, bool:
+		return l == right
+	default:
+		// objects and arrays are only equal to themselves
+		return false
+	}
+}
+
+// compare orders two numbers or two strings, it returns false for other values.
+func compare(left, right interface{}) (int, bool) {
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case l < r:
+			return -1, true
+		case l > r:
+			return 1, true
+		}
+		return 0, true
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case l < r:
+			return -1, true
+		case l > r:
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// truthy converts a value to a boolean.
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case float64:
+		return v != 0
+	default:
+		return true
+	}
+}
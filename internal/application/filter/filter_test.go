@@ -0,0 +1,105 @@
+package filter
+
+import (
+	"anyker/internal/domain"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		expected   string
+	}{
+		{name: "empty", expression: "", expected: "unexpected end of expression"},
+		{name: "unknown field", expression: `body.type == "text"`, expected: "unknown field"},
+		{name: "header without name", expression: `headers == "text"`, expected: "header name"},
+		{name: "unknown key field", expression: `key.user == "a"`, expected: "origin and routing_id"},
+		{name: "topic with fields", expression: `topic.name == "a"`, expected: "topic has no fields"},
+		{name: "unterminated string", expression: `headers.type == "text`, expected: "unterminated string"},
+		{name: "unexpected character", expression: `headers.type = "text"`, expected: "unexpected character"},
+		{name: "missing parenthesis", expression: `(headers.type == "text"`, expected: "expected )"},
+		{name: "trailing tokens", expression: `headers.type == "text" "more"`, expected: "unexpected"},
+		{name: "regex on non-string", expression: `key =~ 1`, expected: "must be a string"},
+		{name: "invalid regex", expression: `key =~ "("`, expected: "invalid regular expression"},
+		{name: "missing operand", expression: `headers.type ==`, expected: "unexpected end of expression"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile(tt.expression)
+
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expected)
+		})
+	}
+}
+
+func TestFilter_Match(t *testing.T) {
+	message := domain.Message{
+		Key:     "telegram:user-123",
+		Topic:   "chat-events",
+		Headers: map[string]string{"type": "text", "x-priority": "high"},
+		Content: []byte(`{"chat":{"id":42,"title":"general"},"items":[{"id":"a"},{"id":"b"}],"draft":false,"text":"hello"}`),
+	}
+
+	tests := []struct {
+		name       string
+		expression string
+		expected   bool
+	}{
+		{name: "header equal", expression: `headers.type == "text"`, expected: true},
+		{name: "header not equal", expression: `headers.type != "text"`, expected: false},
+		{name: "header with dash", expression: `headers.x-priority == 'high'`, expected: true},
+		{name: "header with brackets", expression: `headers["x-priority"] == "high"`, expected: true},
+		{name: "missing header is null", expression: `headers.missing == null`, expected: true},
+		{name: "key", expression: `key == "telegram:user-123"`, expected: true},
+		{name: "key origin", expression: `key.origin == "telegram"`, expected: true},
+		{name: "key routing id", expression: `key.routing_id == "user-123"`, expected: true},
+		{name: "topic", expression: `topic == "chat-events"`, expected: true},
+		{name: "payload number", expression: `payload.chat.id == 42`, expected: true},
+		{name: "payload not null", expression: `payload.chat.id != null`, expected: true},
+		{name: "payload missing", expression: `payload.chat.owner == null`, expected: true},
+		{name: "payload array index", expression: `payload.items[1].id == "b"`, expected: true},
+		{name: "payload array out of range", expression: `payload.items[5].id == null`, expected: true},
+		{name: "payload bool", expression: `payload.draft == false`, expected: true},
+		{name: "number comparison", expression: `payload.chat.id >= 40 && payload.chat.id < 50`, expected: true},
+		{name: "string comparison", expression: `payload.chat.title > "a"`, expected: true},
+		{name: "comparison of different types", expression: `payload.chat.id > "a"`, expected: false},
+		{name: "equality of different types", expression: `payload.chat.id == "42"`, expected: false},
+		{name: "regex", expression: `key.origin =~ "^tele"`, expected: true},
+		{name: "regex on null", expression: `headers.missing =~ ".*"`, expected: false},
+		{name: "and", expression: `headers.type == "text" && payload.chat.id != null`, expected: true},
+		{name: "or", expression: `headers.type == "image" || topic == "chat-events"`, expected: true},
+		{name: "not", expression: `!(headers.type == "image")`, expected: true},
+		{name: "precedence", expression: `headers.type == "image" && false || true`, expected: true},
+		{name: "truthy field", expression: `payload.text`, expected: true},
+		{name: "falsy field", expression: `payload.draft || headers.missing`, expected: false},
+		{name: "negative number", expression: `payload.chat.id > -1`, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := Compile(tt.expression)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.expected, filter.Match(message))
+		})
+	}
+}
+
+func TestFilter_Match_InvalidPayload(t *testing.T) {
+	filter, err := Compile(`payload.chat.id == null`)
+	assert.NoError(t, err)
+
+	assert.True(t, filter.Match(domain.Message{Content: []byte("not json")}))
+}
+
+func TestFilter_String(t *testing.T) {
+	filter, err := Compile(`topic == "a"`)
+	assert.NoError(t, err)
+
+	assert.Equal(t, `topic == "a"`, filter.String())
+}
@@ -0,0 +1,321 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// tokenKind is the kind of a lexical token.
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenLBracket
+	tokenRBracket
+	tokenDot
+)
+
+// token is a lexical token of an expression.
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+// operators sorted so that longer operators are matched first.
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "<", ">", "!"}
+
+// tokenize splits an expression into tokens.
+func tokenize(expression string) ([]token, error) {
+	var tokens []token
+	for pos := 0; pos < len(expression); {
+		c := rune(expression[pos])
+		switch {
+		case unicode.IsSpace(c):
+			pos++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, value: "(", pos: pos})
+			pos++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, value: ")", pos: pos})
+			pos++
+		case c == '[':
+			tokens = append(tokens, token{kind: tokenLBracket, value: "[", pos: pos})
+			pos++
+		case c == ']':
+			tokens = append(tokens, token{kind: tokenRBracket, value: "]", pos: pos})
+			pos++
+		case c == '.':
+			tokens = append(tokens, token{kind: tokenDot, value: ".", pos: pos})
+			pos++
+		case c == '"' || c == '\'':
+			end := pos + 1
+			for end < len(expression) && rune(expression[end]) != c {
+				if expression[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(expression) {
+				return nil, fmt.Errorf("unterminated string at position %d", pos)
+			}
+			value, err := unquote(expression[pos : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at position %d: %w", pos, err)
+			}
+			tokens = append(tokens, token{kind: tokenString, value: value, pos: pos})
+			pos = end + 1
+		case unicode.IsDigit(c) || (c == '-' && pos+1 < len(expression) && unicode.IsDigit(rune(expression[pos+1]))):
+			end := pos + 1
+			for end < len(expression) && (unicode.IsDigit(rune(expression[end])) || expression[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, value: expression[pos:end], pos: pos})
+			pos = end
+		case unicode.IsLetter(c) || c == '_':
+			end := pos + 1
+			for end < len(expression) && isIdentChar(rune(expression[end])) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, value: expression[pos:end], pos: pos})
+			pos = end
+		default:
+			operator := ""
+			for _, op := range operators {
+				if strings.HasPrefix(expression[pos:], op) {
+					operator = op
+					break
+				}
+			}
+			if operator == "" {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, pos)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, value: operator, pos: pos})
+			pos += len(operator)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(expression)}), nil
+}
+
+// isIdentChar reports whether c can be part of an identifier.
+func isIdentChar(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' || c == '-'
+}
+
+// unquote unquotes a single or double-quoted string.
+func unquote(s string) (string, error) {
+	if s[0] == '\'' {
+		// reuse the double-quoted string rules for single-quoted strings
+		s = `"` + strings.ReplaceAll(strings.ReplaceAll(s[1:len(s)-1], `\'`, `'`), `"`, `\"`) + `"`
+	}
+	return strconv.Unquote(s)
+}
+
+// parser is a recursive descent parser of filter expressions.
+//
+//	or         := and ( "||" and )*
+//	and        := unary ( "&&" unary )*
+//	unary      := "!" unary | comparison
+//	comparison := operand ( ( "==" | "!=" | "<" | "<=" | ">" | ">=" | "=~" ) operand )?
+//	operand    := string | number | true | false | null | path | "(" or ")"
+//	path       := ident ( "." ident | "[" ( string | number ) "]" )*
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// parse parses an expression into its syntax tree.
+func parse(expression string) (node, error) {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.value, tok.pos)
+	}
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// acceptOperator consumes the next token if it is one of the given operators.
+func (p *parser) acceptOperator(ops ...string) (string, bool) {
+	tok := p.peek()
+	if tok.kind != tokenOperator {
+		return "", false
+	}
+	for _, op := range ops {
+		if tok.value == op {
+			p.next()
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOperator("||"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left: left, right: right}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOperator("&&"); !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if _, ok := p.acceptOperator("!"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.acceptOperator("==", "!=", "<", "<=", ">", ">=", "=~")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if op == "=~" {
+		lit, ok := right.(literalNode)
+		pattern, isString := lit.value.(string)
+		if !ok || !isString {
+			return nil, fmt.Errorf("the right side of =~ must be a string")
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", pattern, err)
+		}
+		return matchNode{operand: left, re: re}, nil
+	}
+	return compareNode{op: op, left: left, right: right}, nil
+}
+
+func (p *parser) parseOperand() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenString:
+		return literalNode{value: tok.value}, nil
+	case tokenNumber:
+		value, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", tok.value, tok.pos)
+		}
+		return literalNode{value: value}, nil
+	case tokenLParen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, fmt.Errorf("expected ) at position %d", closing.pos)
+		}
+		return n, nil
+	case tokenIdent:
+		switch tok.value {
+		case "true":
+			return literalNode{value: true}, nil
+		case "false":
+			return literalNode{value: false}, nil
+		case "null":
+			return literalNode{value: nil}, nil
+		}
+		return p.parsePath(tok)
+	case tokenEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	default:
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.value, tok.pos)
+	}
+}
+
+func (p *parser) parsePath(root token) (node, error) {
+	if !validRoots[root.value] {
+		return nil, fmt.Errorf("unknown field %q at position %d, expected one of headers, key, topic or payload", root.value, root.pos)
+	}
+	path := pathNode{root: root.value}
+	for {
+		switch p.peek().kind {
+		case tokenDot:
+			p.next()
+			tok := p.next()
+			if tok.kind != tokenIdent {
+				return nil, fmt.Errorf("expected field name at position %d", tok.pos)
+			}
+			path.segments = append(path.segments, tok.value)
+		case tokenLBracket:
+			p.next()
+			tok := p.next()
+			if tok.kind != tokenString && tok.kind != tokenNumber {
+				return nil, fmt.Errorf("expected string or index at position %d", tok.pos)
+			}
+			if closing := p.next(); closing.kind != tokenRBracket {
+				return nil, fmt.Errorf("expected ] at position %d", closing.pos)
+			}
+			path.segments = append(path.segments, tok.value)
+		default:
+			if err := path.validate(); err != nil {
+				return nil, fmt.Errorf("%w at position %d", err, root.pos)
+			}
+			return path, nil
+		}
+	}
+}
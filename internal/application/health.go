@@ -0,0 +1,28 @@
+package application
+
+import (
+	"anyker/internal/domain"
+	"errors"
+)
+
+// checkLive joins the Live errors of the components implementing domain.HealthChecker, the others are ignored.
+func checkLive(components ...any) error {
+	var errs []error
+	for _, component := range components {
+		if checker, ok := component.(domain.HealthChecker); ok {
+			errs = append(errs, checker.Live())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkReady joins the Ready errors of the components implementing domain.HealthChecker, the others are ignored.
+func checkReady(components ...any) error {
+	var errs []error
+	for _, component := range components {
+		if checker, ok := component.(domain.HealthChecker); ok {
+			errs = append(errs, checker.Ready())
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,68 @@
+package application
+
+import (
+	"anyker/config"
+	"anyker/internal/domain"
+	"anyker/internal/domain/mocks"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// checkedForwardRepository is a forward repository that reports its health.
+type checkedForwardRepository struct {
+	*mocks.MockForwardRepository
+	*mocks.MockHealthChecker
+}
+
+func newCheckedForwardRepository() checkedForwardRepository {
+	return checkedForwardRepository{new(mocks.MockForwardRepository), new(mocks.MockHealthChecker)}
+}
+
+func TestMessageUsecase_Health(t *testing.T) {
+	t.Run("repositories without health checks", func(t *testing.T) {
+		service, err := NewMessageService(config.Config{}, new(mocks.MockForwardRepository), new(mocks.MockConsumerRepository), nil, nil)
+		assert.NoError(t, err)
+		usecase := service.(*MessageUsecase)
+
+		assert.NoError(t, usecase.Live())
+		assert.NoError(t, usecase.Ready())
+	})
+
+	t.Run("forward repository with health checks", func(t *testing.T) {
+		forwardRepo := newCheckedForwardRepository()
+		service, err := NewMessageService(config.Config{}, forwardRepo, new(mocks.MockConsumerRepository), nil, nil)
+		assert.NoError(t, err)
+		usecase := service.(*MessageUsecase)
+
+		liveErr := errors.New("3 consecutive failures forwarding to http://localhost:8080")
+		forwardRepo.MockHealthChecker.On("Live").Return(liveErr).Once()
+		forwardRepo.MockHealthChecker.On("Ready").Return(nil).Once()
+
+		assert.ErrorIs(t, usecase.Live(), liveErr)
+		assert.NoError(t, usecase.Ready())
+		forwardRepo.MockHealthChecker.AssertExpectations(t)
+	})
+}
+
+func TestRouter_Health(t *testing.T) {
+	telegramRepo := newCheckedForwardRepository()
+	whatsappRepo := newCheckedForwardRepository()
+	router := NewRouter(map[string]domain.ForwardRepository{
+		"telegram": telegramRepo,
+		"whatsapp": whatsappRepo,
+		"slack":    new(mocks.MockForwardRepository),
+	}).(*Router)
+
+	liveErr := errors.New("2 consecutive failures forwarding to http://bot-b/messages")
+	telegramRepo.MockHealthChecker.On("Live").Return(nil).Once()
+	whatsappRepo.MockHealthChecker.On("Live").Return(liveErr).Once()
+	telegramRepo.MockHealthChecker.On("Ready").Return(nil).Once()
+	whatsappRepo.MockHealthChecker.On("Ready").Return(nil).Once()
+
+	assert.ErrorIs(t, router.Live(), liveErr)
+	assert.NoError(t, router.Ready())
+	telegramRepo.MockHealthChecker.AssertExpectations(t)
+	whatsappRepo.MockHealthChecker.AssertExpectations(t)
+}
@@ -0,0 +1,98 @@
+package application
+
+import (
+	"anyker/config"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// otherOrigin is the metric label of the origins that are not known.
+const otherOrigin = "other"
+
+// originFilter decides which message origins are forwarded, using an allowlist and a denylist of glob patterns
+// (e.g. telegram, whats*). An empty allowlist allows every origin, the denylist takes precedence over the allowlist.
+type originFilter struct {
+	include []string
+	exclude []string
+}
+
+// newOriginFilter creates an originFilter from comma-separated lists of patterns.
+// It returns an error if a pattern is invalid, as ignoring it could forward every origin.
+func newOriginFilter(include, exclude string) (originFilter, error) {
+	includePatterns, err := parsePatterns(include)
+	if err != nil {
+		return originFilter{}, err
+	}
+	excludePatterns, err := parsePatterns(exclude)
+	if err != nil {
+		return originFilter{}, err
+	}
+	return originFilter{include: includePatterns, exclude: excludePatterns}, nil
+}
+
+// allows reports whether messages from the origin are forwarded.
+func (f originFilter) allows(origin string) bool {
+	if matchesAny(f.exclude, origin) {
+		return false
+	}
+	return len(f.include) == 0 || matchesAny(f.include, origin)
+}
+
+// parsePatterns splits a comma-separated list of glob patterns, validating them.
+func parsePatterns(value string) ([]string, error) {
+	var patterns []string
+	for _, pattern := range strings.Split(value, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid origin pattern %s: %w", pattern, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// matchesAny reports whether the origin matches any of the patterns.
+func matchesAny(patterns []string, origin string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, origin); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// originLabels bounds the origin label of the metrics, as the origins come from the message keys:
+// only the known origins are labelled by name, the others are labelled as other.
+type originLabels map[string]bool
+
+// newOriginLabels creates the originLabels of the origins listed in the metrics configuration,
+// the literal patterns of the origin filter and the routes.
+func newOriginLabels(config config.Config, filter originFilter) originLabels {
+	labels := make(originLabels)
+	for _, origin := range config.MetricsOrigins {
+		labels[origin] = true
+	}
+	for _, pattern := range append(filter.include, filter.exclude...) {
+		if !strings.ContainsAny(pattern, `*?[\`) {
+			labels[pattern] = true
+		}
+	}
+	for origin := range config.Routes {
+		if origin != DefaultRoute {
+			labels[origin] = true
+		}
+	}
+	return labels
+}
+
+// label returns the metric label of the origin.
+func (l originLabels) label(origin string) string {
+	if l[origin] {
+		return origin
+	}
+	return otherOrigin
+}
@@ -0,0 +1,79 @@
+package application
+
+import (
+	"anyker/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOriginFilter_Allows(t *testing.T) {
+	tests := []struct {
+		name     string
+		include  string
+		exclude  string
+		origin   string
+		expected bool
+	}{
+		{name: "empty filter allows everything", origin: "telegram", expected: true},
+		{name: "single origin match", include: "telegram", origin: "telegram", expected: true},
+		{name: "single origin mismatch", include: "telegram", origin: "whatsapp", expected: false},
+		{name: "allowlist match", include: "telegram, whatsapp", origin: "whatsapp", expected: true},
+		{name: "allowlist mismatch", include: "telegram,whatsapp", origin: "slack", expected: false},
+		{name: "allowlist glob", include: "whats*", origin: "whatsapp-business", expected: true},
+		{name: "denylist", exclude: "test", origin: "test", expected: false},
+		{name: "denylist glob", exclude: "test-*", origin: "test-telegram", expected: false},
+		{name: "denylist mismatch", exclude: "test", origin: "telegram", expected: true},
+		{name: "denylist takes precedence", include: "*", exclude: "test", origin: "test", expected: false},
+		{name: "empty origin", include: "telegram", origin: "", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := newOriginFilter(tt.include, tt.exclude)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.expected, filter.allows(tt.origin))
+		})
+	}
+}
+
+func TestNewOriginFilter_InvalidPattern(t *testing.T) {
+	// a typo in the allowlist must not forward every origin
+	_, err := newOriginFilter("[telegram", "")
+	assert.EqualError(t, err, "invalid origin pattern [telegram: syntax error in pattern")
+
+	_, err = newOriginFilter("telegram", "test,[staging")
+	assert.EqualError(t, err, "invalid origin pattern [staging: syntax error in pattern")
+}
+
+func TestOriginLabels_Label(t *testing.T) {
+	cfg := config.Config{
+		MetricsOrigins: []string{"slack"},
+		Origin:         "telegram,whats*",
+		OriginExclude:  "test",
+		Routes:         map[string]string{"discord": "http://bot-a/messages", "*": "http://bot-b/messages"},
+	}
+	filter, err := newOriginFilter(cfg.Origin, cfg.OriginExclude)
+	assert.NoError(t, err)
+	labels := newOriginLabels(cfg, filter)
+
+	tests := []struct {
+		origin   string
+		expected string
+	}{
+		{origin: "slack", expected: "slack"},
+		{origin: "telegram", expected: "telegram"},
+		{origin: "test", expected: "test"},
+		{origin: "discord", expected: "discord"},
+		{origin: "whatsapp", expected: "other"},
+		{origin: "whats*", expected: "other"},
+		{origin: "*", expected: "other"},
+		{origin: "random-origin-1234", expected: "other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			assert.Equal(t, tt.expected, labels.label(tt.origin))
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package application
+
+import (
+	"anyker/internal/domain"
+	"context"
+	"fmt"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultRoute is the route used for the origins without a route of their own.
+const DefaultRoute = "*"
+
+// Router is a domain.ForwardRepository that forwards each message to the repository of its origin.
+type Router struct {
+	routes map[string]domain.ForwardRepository
+}
+
+// NewRouter creates a new Router with the given forward repositories by origin.
+// The DefaultRoute is optional, without it messages from other origins are not forwarded.
+func NewRouter(routes map[string]domain.ForwardRepository) domain.ForwardRepository {
+	return &Router{
+		routes: routes,
+	}
+}
+
+// Forward forwards a message to the repository of its origin, or to the default route.
+// It returns domain.ErrNoRoute if there is none.
+func (r *Router) Forward(ctx context.Context, message domain.Message) error {
+	origin, _ := domain.ParseKey(message.Key)
+
+	route := origin
+	forwardRepository, ok := r.routes[route]
+	if !ok {
+		route = DefaultRoute
+		forwardRepository, ok = r.routes[route]
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s", domain.ErrNoRoute, origin)
+	}
+	log.Ctx(ctx).Debug().Msgf("message origin: %s routed to %s", origin, route)
+
+	return forwardRepository.Forward(ctx, message)
+}
+
+// Live returns an error when the repository of any route is not live.
+func (r *Router) Live() error {
+	return checkLive(r.repositories()...)
+}
+
+// Ready returns an error when the repository of any route is not ready.
+func (r *Router) Ready() error {
+	return checkReady(r.repositories()...)
+}
+
+// repositories returns the forward repositories of every route.
+func (r *Router) repositories() []any {
+	repositories := make([]any, 0, len(r.routes))
+	for _, repository := range r.routes {
+		repositories = append(repositories, repository)
+	}
+	return repositories
+}
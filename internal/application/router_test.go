@@ -0,0 +1,71 @@
+package application
+
+import (
+	"anyker/internal/domain"
+	"anyker/internal/domain/mocks"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouter_Forward(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("routes by origin", func(t *testing.T) {
+		telegramRepo := new(mocks.MockForwardRepository)
+		whatsappRepo := new(mocks.MockForwardRepository)
+		router := NewRouter(map[string]domain.ForwardRepository{
+			"telegram": telegramRepo,
+			"whatsapp": whatsappRepo,
+		})
+
+		telegramMsg := domain.Message{Key: "telegram:user-1", Content: []byte("hello")}
+		whatsappMsg := domain.Message{Key: "whatsapp:user-2", Content: []byte("hola")}
+		telegramRepo.On("Forward", ctx, telegramMsg).Return(nil).Once()
+		whatsappRepo.On("Forward", ctx, whatsappMsg).Return(nil).Once()
+
+		assert.NoError(t, router.Forward(ctx, telegramMsg))
+		assert.NoError(t, router.Forward(ctx, whatsappMsg))
+		telegramRepo.AssertExpectations(t)
+		whatsappRepo.AssertExpectations(t)
+	})
+
+	t.Run("default route", func(t *testing.T) {
+		telegramRepo := new(mocks.MockForwardRepository)
+		defaultRepo := new(mocks.MockForwardRepository)
+		router := NewRouter(map[string]domain.ForwardRepository{
+			"telegram":   telegramRepo,
+			DefaultRoute: defaultRepo,
+		})
+
+		msg := domain.Message{Key: "slack:user-3", Content: []byte("hi")}
+		defaultRepo.On("Forward", ctx, msg).Return(nil).Once()
+
+		assert.NoError(t, router.Forward(ctx, msg))
+		defaultRepo.AssertExpectations(t)
+		telegramRepo.AssertNotCalled(t, "Forward")
+	})
+
+	t.Run("no route", func(t *testing.T) {
+		telegramRepo := new(mocks.MockForwardRepository)
+		router := NewRouter(map[string]domain.ForwardRepository{"telegram": telegramRepo})
+
+		err := router.Forward(ctx, domain.Message{Key: "slack:user-3"})
+
+		assert.ErrorIs(t, err, domain.ErrNoRoute)
+		assert.Contains(t, err.Error(), "slack")
+	})
+
+	t.Run("forward error", func(t *testing.T) {
+		telegramRepo := new(mocks.MockForwardRepository)
+		router := NewRouter(map[string]domain.ForwardRepository{"telegram": telegramRepo})
+
+		msg := domain.Message{Key: "telegram:user-1"}
+		expectedErr := errors.New("forward error")
+		telegramRepo.On("Forward", ctx, msg).Return(expectedErr).Once()
+
+		assert.Equal(t, expectedErr, router.Forward(ctx, msg))
+	})
+}
@@ -2,43 +2,123 @@ package application
 
 import (
 	"anyker/config"
+	"anyker/internal/application/filter"
 	"anyker/internal/domain"
+	"anyker/internal/metrics"
 	"context"
+	"errors"
+	"fmt"
 	"github.com/rs/zerolog/log"
-	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// tracerName is the name of the tracer of the use cases.
+const tracerName = "anyker/internal/application"
+
 // MessageUsecase is the implementation of the MessageUseCase.
 type MessageUsecase struct {
-	forwardRepository  domain.ForwardRepository
-	consumerRepository domain.ConsumerRepository
-	config             config.Config
+	forwardRepository    domain.ForwardRepository
+	consumerRepository   domain.ConsumerRepository
+	deadLetterRepository domain.DeadLetterRepository
+	config               config.Config
+	originFilter         originFilter
+	originLabels         originLabels
+	messageFilter        *filter.Filter
+	// messagingSystem is the messaging.system attribute of the source, for the forward spans
+	messagingSystem attribute.KeyValue
 }
 
 // NewMessageService creates a new MessageUsecase with the given repositories.
+// The dead-letter repository is optional, when nil failed messages are only logged.
+// The message filter is optional too, when nil every message from an allowed origin is forwarded.
 func NewMessageService(
 	config config.Config,
 	forwardRepository domain.ForwardRepository,
-	consumerRepository domain.ConsumerRepository) domain.MessageUseCase {
+	consumerRepository domain.ConsumerRepository,
+	deadLetterRepository domain.DeadLetterRepository,
+	messageFilter *filter.Filter) (domain.MessageUseCase, error) {
+	originFilter, err := newOriginFilter(config.Origin, config.OriginExclude)
+	if err != nil {
+		return nil, err
+	}
 	return &MessageUsecase{
-		forwardRepository:  forwardRepository,
-		consumerRepository: consumerRepository,
-		config:             config,
+		forwardRepository:    forwardRepository,
+		consumerRepository:   consumerRepository,
+		deadLetterRepository: deadLetterRepository,
+		config:               config,
+		originFilter:         originFilter,
+		originLabels:         newOriginLabels(config, originFilter),
+		messageFilter:        messageFilter,
+		messagingSystem:      messagingSystem(config.Source()),
+	}, nil
+}
+
+// messagingSystem returns the messaging.system attribute of a source, see config.Config.Source.
+func messagingSystem(source string) attribute.KeyValue {
+	switch source {
+	case config.SourceKafka:
+		return semconv.MessagingSystemKafka
+	case config.SourceAMQP:
+		return semconv.MessagingSystemRabbitmq
+	default:
+		return semconv.MessagingSystemKey.String(source)
 	}
 }
 
 // Forward forwards a message using the forward repository.
+// If forwarding fails and a dead-letter repository is configured, the message is published
+// as a dead letter instead, and an error is only returned if publishing fails too.
+// It is traced in a span child of the trace context of the message headers.
 func (u *MessageUsecase) Forward(ctx context.Context, message domain.Message) error {
-	if u.config.Origin == "" {
-		log.Debug().Msg("all messages will be read")
-	} else {
-		origin, _ := u.getOriginAndRoutingID(message.Key)
-		if u.config.Origin != origin {
-			log.Debug().Msgf("message origin: %s discarded", origin)
-			return nil
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(message.Headers))
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "forward "+message.Topic,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			u.messagingSystem,
+			semconv.MessagingOperationTypeDeliver,
+			semconv.MessagingDestinationName(message.Topic),
+			semconv.MessagingMessageID(message.CorrelationID),
+		))
+	defer span.End()
+
+	err := u.forward(ctx, message)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// forward filters and forwards a message, see Forward.
+func (u *MessageUsecase) forward(ctx context.Context, message domain.Message) error {
+	origin, _ := u.getOriginAndRoutingID(message.Key)
+	if reason := u.filter(ctx, origin, message); reason != "" {
+		u.discard(ctx, origin, reason)
+		return nil
+	}
+	err := u.forwardRepository.Forward(ctx, message)
+	if errors.Is(err, domain.ErrNoRoute) {
+		u.discard(ctx, origin, "no route")
+		return nil
+	}
+	if err == nil {
+		metrics.MessagesForwarded.WithLabelValues(u.originLabels.label(origin)).Inc()
+		if !message.Timestamp.IsZero() {
+			metrics.EndToEndLag.Observe(time.Since(message.Timestamp).Seconds())
 		}
 	}
-	return u.forwardRepository.Forward(ctx, message)
+	if err == nil || u.deadLetterRepository == nil || ctx.Err() != nil {
+		// a forward interrupted by a shutdown is not a permanent failure
+		return err
+	}
+	return u.publishDeadLetter(ctx, message, err)
 }
 
 // Consume consumes messages from the consumer repository.
@@ -46,22 +126,93 @@ func (u *MessageUsecase) Consume(ctx context.Context, messages chan<- *domain.Me
 	return u.consumerRepository.Consume(ctx, messages)
 }
 
-// Close closes the underlying consumer repository.
+// Commit commits a processed message using the consumer repository.
+func (u *MessageUsecase) Commit(ctx context.Context, message domain.Message) error {
+	return u.consumerRepository.Commit(ctx, message)
+}
+
+// Reject rejects a message that could not be forwarded if the consumer supports it, otherwise it is left uncommitted.
+func (u *MessageUsecase) Reject(ctx context.Context, message domain.Message) error {
+	if rejecter, ok := u.consumerRepository.(domain.Rejecter); ok {
+		return rejecter.Reject(ctx, message)
+	}
+	return nil
+}
+
+// Close closes the underlying consumer and dead-letter repositories.
 func (u *MessageUsecase) Close() error {
-	return u.consumerRepository.Close()
+	err := u.consumerRepository.Close()
+	if u.deadLetterRepository != nil {
+		err = errors.Join(err, u.deadLetterRepository.Close())
+	}
+	return err
+}
+
+// Live returns an error when the consumer or forward repository is wedged.
+// Repositories that do not implement domain.HealthChecker are always live.
+func (u *MessageUsecase) Live() error {
+	return checkLive(u.consumerRepository, u.forwardRepository)
+}
+
+// Ready returns an error when the consumer or forward repository is not ready to process messages.
+// Repositories that do not implement domain.HealthChecker are always ready.
+func (u *MessageUsecase) Ready() error {
+	return checkReady(u.consumerRepository, u.forwardRepository)
+}
+
+// filter returns why a message from the origin is discarded by the origin and expression filters,
+// or an empty reason if it is forwarded.
+func (u *MessageUsecase) filter(ctx context.Context, origin string, message domain.Message) (reason string) {
+	_, span := otel.Tracer(tracerName).Start(ctx, "filter")
+	defer func() {
+		span.SetAttributes(attribute.Bool("anyker.filter.discarded", reason != ""))
+		if reason != "" {
+			span.SetAttributes(attribute.String("anyker.filter.reason", reason))
+		}
+		span.End()
+	}()
+
+	if !u.originFilter.allows(origin) {
+		return "origin filter"
+	}
+	if u.messageFilter != nil && !u.messageFilter.Match(message) {
+		return "expression filter"
+	}
+	return ""
+}
+
+// discard counts and logs a message that is not forwarded.
+func (u *MessageUsecase) discard(ctx context.Context, origin string, reason string) {
+	metrics.MessagesFiltered.WithLabelValues(u.originLabels.label(origin), reason).Inc()
+
+	log.Ctx(ctx).Debug().Str("origin", origin).Str("reason", reason).Msgf("message origin: %s discarded", origin)
+}
+
+// publishDeadLetter publishes a message that failed to be forwarded, along with the failure metadata.
+func (u *MessageUsecase) publishDeadLetter(ctx context.Context, message domain.Message, forwardErr error) error {
+	deadLetter := domain.DeadLetter{
+		Message:     message,
+		Error:       forwardErr.Error(),
+		Attempts:    1,
+		NanobotName: u.config.NanobotName,
+		Timestamp:   time.Now(),
+	}
+	var fe *domain.ForwardError
+	if errors.As(forwardErr, &fe) {
+		deadLetter.StatusCode = fe.StatusCode
+		deadLetter.Attempts = fe.Attempts
+	}
+
+	if err := u.deadLetterRepository.Publish(ctx, deadLetter); err != nil {
+		return fmt.Errorf("failed to publish dead letter: %w (forward error: %v)", err, forwardErr)
+	}
+	log.Ctx(ctx).Warn().Err(forwardErr).Msgf("message with key %s sent to the dead-letter topic", message.Key)
+
+	return nil
 }
 
 // getOriginAndRoutingID extracts the origin and routing ID from a given key.
 // If the key does not contain a colon, the entire key is treated as the origin, and routingId remains empty.
 func (u *MessageUsecase) getOriginAndRoutingID(key string) (origin string, routingId string) {
-	keyParts := strings.SplitN(key, ":", 2)
-
-	if len(keyParts) == 2 {
-		origin = keyParts[0]
-		routingId = keyParts[1]
-	} else {
-		// the entire key is taken as origin, userID remains empty
-		origin = keyParts[0]
-	}
-	return
+	return domain.ParseKey(key)
 }
@@ -2,25 +2,40 @@ package application
 
 import (
 	"anyker/config"
+	"anyker/internal/application/filter"
 	"anyker/internal/domain"
 	"anyker/internal/domain/mocks"
+	"anyker/internal/metrics"
 	"context"
 	"errors"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestMessageUsecase_Forward(t *testing.T) {
 	mockForwardRepo := new(mocks.MockForwardRepository)
 	cfg := config.Config{} // or initialize with specific values if necessary
-	usecase := NewMessageService(cfg, mockForwardRepo, nil)
+	usecase, err := NewMessageService(cfg, mockForwardRepo, nil, nil, nil)
+	assert.NoError(t, err)
 
 	ctx := context.Background()
 	msg := domain.Message{Content: []byte("hello")}
 
 	t.Run("success", func(t *testing.T) {
-		mockForwardRepo.On("Forward", ctx, msg).Return(nil).Once()
+		mockForwardRepo.On("Forward", mock.Anything, msg).Return(nil).Once()
 
 		err := usecase.Forward(ctx, msg)
 
@@ -30,7 +45,7 @@ func TestMessageUsecase_Forward(t *testing.T) {
 
 	t.Run("error", func(t *testing.T) {
 		expectedErr := errors.New("forward error")
-		mockForwardRepo.On("Forward", ctx, msg).Return(expectedErr).Once()
+		mockForwardRepo.On("Forward", mock.Anything, msg).Return(expectedErr).Once()
 
 		err := usecase.Forward(ctx, msg)
 
@@ -40,22 +55,126 @@ func TestMessageUsecase_Forward(t *testing.T) {
 	})
 }
 
+func TestMessageUsecase_Forward_NoRoute(t *testing.T) {
+	mockForwardRepo := new(mocks.MockForwardRepository)
+	mockDeadLetterRepo := new(mocks.MockDeadLetterRepository)
+	usecase, err := NewMessageService(config.Config{}, mockForwardRepo, nil, mockDeadLetterRepo, nil)
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	msg := domain.Message{Key: "slack:user-3", Content: []byte("test message")}
+	mockForwardRepo.On("Forward", mock.Anything, msg).Return(fmt.Errorf("%w: slack", domain.ErrNoRoute)).Once()
+
+	err = usecase.Forward(ctx, msg)
+
+	// discarded, not dead-lettered
+	assert.NoError(t, err)
+	mockForwardRepo.AssertExpectations(t)
+	mockDeadLetterRepo.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+}
+
+func TestMessageUsecase_Forward_DeadLetter(t *testing.T) {
+	cfg := config.Config{NanobotName: "test-nanobot"}
+	ctx := context.Background()
+	msg := domain.Message{
+		Key:     "service-a:user-123",
+		Content: []byte("test message"),
+		Headers: map[string]string{"correlation_id": "123"},
+	}
+	matchesDeadLetter := func(statusCode, attempts int) interface{} {
+		return mock.MatchedBy(func(d domain.DeadLetter) bool {
+			return assert.ObjectsAreEqual(msg, d.Message) &&
+				d.StatusCode == statusCode &&
+				d.Attempts == attempts &&
+				d.NanobotName == "test-nanobot" &&
+				d.Error != "" &&
+				!d.Timestamp.IsZero()
+		})
+	}
+
+	t.Run("failed message is published as a dead letter", func(t *testing.T) {
+		mockForwardRepo := new(mocks.MockForwardRepository)
+		mockDeadLetterRepo := new(mocks.MockDeadLetterRepository)
+		usecase, err := NewMessageService(cfg, mockForwardRepo, nil, mockDeadLetterRepo, nil)
+		assert.NoError(t, err)
+
+		forwardErr := &domain.ForwardError{StatusCode: 503, Attempts: 3, Err: errors.New("unexpected status code: 503")}
+		mockForwardRepo.On("Forward", mock.Anything, msg).Return(forwardErr).Once()
+		mockDeadLetterRepo.On("Publish", mock.Anything, matchesDeadLetter(503, 3)).Return(nil).Once()
+
+		err = usecase.Forward(ctx, msg)
+
+		assert.NoError(t, err)
+		mockForwardRepo.AssertExpectations(t)
+		mockDeadLetterRepo.AssertExpectations(t)
+	})
+
+	t.Run("plain forward error counts as one attempt", func(t *testing.T) {
+		mockForwardRepo := new(mocks.MockForwardRepository)
+		mockDeadLetterRepo := new(mocks.MockDeadLetterRepository)
+		usecase, err := NewMessageService(cfg, mockForwardRepo, nil, mockDeadLetterRepo, nil)
+		assert.NoError(t, err)
+
+		mockForwardRepo.On("Forward", mock.Anything, msg).Return(errors.New("forward error")).Once()
+		mockDeadLetterRepo.On("Publish", mock.Anything, matchesDeadLetter(0, 1)).Return(nil).Once()
+
+		err = usecase.Forward(ctx, msg)
+
+		assert.NoError(t, err)
+		mockDeadLetterRepo.AssertExpectations(t)
+	})
+
+	t.Run("dead letter publish error", func(t *testing.T) {
+		mockForwardRepo := new(mocks.MockForwardRepository)
+		mockDeadLetterRepo := new(mocks.MockDeadLetterRepository)
+		usecase, err := NewMessageService(cfg, mockForwardRepo, nil, mockDeadLetterRepo, nil)
+		assert.NoError(t, err)
+
+		publishErr := errors.New("broker unavailable")
+		mockForwardRepo.On("Forward", mock.Anything, msg).Return(errors.New("forward error")).Once()
+		mockDeadLetterRepo.On("Publish", mock.Anything, mock.Anything).Return(publishErr).Once()
+
+		err = usecase.Forward(ctx, msg)
+
+		assert.ErrorIs(t, err, publishErr)
+		assert.Contains(t, err.Error(), "forward error")
+		mockDeadLetterRepo.AssertExpectations(t)
+	})
+
+	t.Run("cancelled context is not dead-lettered", func(t *testing.T) {
+		mockForwardRepo := new(mocks.MockForwardRepository)
+		mockDeadLetterRepo := new(mocks.MockDeadLetterRepository)
+		usecase, err := NewMessageService(cfg, mockForwardRepo, nil, mockDeadLetterRepo, nil)
+		assert.NoError(t, err)
+
+		cancelledCtx, cancel := context.WithCancel(ctx)
+		cancel()
+		mockForwardRepo.On("Forward", mock.Anything, msg).Return(context.Canceled).Once()
+
+		err = usecase.Forward(cancelledCtx, msg)
+
+		assert.ErrorIs(t, err, context.Canceled)
+		mockDeadLetterRepo.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+	})
+}
+
 func TestMessageUsecase_Forward_OriginFiltering(t *testing.T) {
 	mockForwardRepo := new(mocks.MockForwardRepository)
 	ctx := context.Background()
 
 	t.Run("empty origin config - all messages processed", func(t *testing.T) {
 		cfg := config.Config{Origin: ""} // Empty origin means process all
-		usecase := NewMessageService(cfg, mockForwardRepo, nil)
+		usecase, err := NewMessageService(cfg, mockForwardRepo, nil, nil, nil)
+		assert.NoError(t, err)
 
 		msg := domain.Message{
 			Key:     "any-origin:routing-id",
 			Content: []byte("test message"),
 		}
 
-		mockForwardRepo.On("Forward", ctx, msg).Return(nil).Once()
+		mockForwardRepo.On("Forward", mock.Anything, msg).Return(nil).Once()
 
-		err := usecase.Forward(ctx, msg)
+		err = usecase.Forward(ctx, msg)
 
 		assert.NoError(t, err)
 		mockForwardRepo.AssertExpectations(t)
@@ -63,16 +182,17 @@ func TestMessageUsecase_Forward_OriginFiltering(t *testing.T) {
 
 	t.Run("matching origin - message forwarded", func(t *testing.T) {
 		cfg := config.Config{Origin: "service-a"}
-		usecase := NewMessageService(cfg, mockForwardRepo, nil)
+		usecase, err := NewMessageService(cfg, mockForwardRepo, nil, nil, nil)
+		assert.NoError(t, err)
 
 		msg := domain.Message{
 			Key:     "service-a:user-123",
 			Content: []byte("test message"),
 		}
 
-		mockForwardRepo.On("Forward", ctx, msg).Return(nil).Once()
+		mockForwardRepo.On("Forward", mock.Anything, msg).Return(nil).Once()
 
-		err := usecase.Forward(ctx, msg)
+		err = usecase.Forward(ctx, msg)
 
 		assert.NoError(t, err)
 		mockForwardRepo.AssertExpectations(t)
@@ -80,7 +200,8 @@ func TestMessageUsecase_Forward_OriginFiltering(t *testing.T) {
 
 	t.Run("non-matching origin - message discarded", func(t *testing.T) {
 		cfg := config.Config{Origin: "service-a"}
-		usecase := NewMessageService(cfg, mockForwardRepo, nil)
+		usecase, err := NewMessageService(cfg, mockForwardRepo, nil, nil, nil)
+		assert.NoError(t, err)
 
 		msg := domain.Message{
 			Key:     "service-b:user-123",
@@ -90,7 +211,7 @@ func TestMessageUsecase_Forward_OriginFiltering(t *testing.T) {
 		// Should not call Forward because origin doesn't match
 		// mockForwardRepo has no expectations, so if called it will fail
 
-		err := usecase.Forward(ctx, msg)
+		err = usecase.Forward(ctx, msg)
 
 		assert.NoError(t, err) // No error, but also not forwarded
 		mockForwardRepo.AssertExpectations(t)
@@ -98,16 +219,17 @@ func TestMessageUsecase_Forward_OriginFiltering(t *testing.T) {
 
 	t.Run("key without colon - origin is entire key", func(t *testing.T) {
 		cfg := config.Config{Origin: "simple-key"}
-		usecase := NewMessageService(cfg, mockForwardRepo, nil)
+		usecase, err := NewMessageService(cfg, mockForwardRepo, nil, nil, nil)
+		assert.NoError(t, err)
 
 		msg := domain.Message{
 			Key:     "simple-key",
 			Content: []byte("test message"),
 		}
 
-		mockForwardRepo.On("Forward", ctx, msg).Return(nil).Once()
+		mockForwardRepo.On("Forward", mock.Anything, msg).Return(nil).Once()
 
-		err := usecase.Forward(ctx, msg)
+		err = usecase.Forward(ctx, msg)
 
 		assert.NoError(t, err)
 		mockForwardRepo.AssertExpectations(t)
@@ -115,7 +237,8 @@ func TestMessageUsecase_Forward_OriginFiltering(t *testing.T) {
 
 	t.Run("key without colon - non-matching origin", func(t *testing.T) {
 		cfg := config.Config{Origin: "expected-key"}
-		usecase := NewMessageService(cfg, mockForwardRepo, nil)
+		usecase, err := NewMessageService(cfg, mockForwardRepo, nil, nil, nil)
+		assert.NoError(t, err)
 
 		msg := domain.Message{
 			Key:     "different-key",
@@ -123,13 +246,109 @@ func TestMessageUsecase_Forward_OriginFiltering(t *testing.T) {
 		}
 
 		// No expectations - shouldn't be called
-		err := usecase.Forward(ctx, msg)
+		err = usecase.Forward(ctx, msg)
 
 		assert.NoError(t, err)
 		mockForwardRepo.AssertExpectations(t)
 	})
 }
 
+func TestMessageUsecase_Forward_OriginLists(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("allowlist and denylist", func(t *testing.T) {
+		mockForwardRepo := new(mocks.MockForwardRepository)
+		cfg := config.Config{Origin: "telegram,whats*", OriginExclude: "whatsapp-test"}
+		usecase, err := NewMessageService(cfg, mockForwardRepo, nil, nil, nil)
+		assert.NoError(t, err)
+
+		telegramMsg := domain.Message{Key: "telegram:user-1"}
+		whatsappMsg := domain.Message{Key: "whatsapp:user-2"}
+		mockForwardRepo.On("Forward", mock.Anything, telegramMsg).Return(nil).Once()
+		mockForwardRepo.On("Forward", mock.Anything, whatsappMsg).Return(nil).Once()
+
+		assert.NoError(t, usecase.Forward(ctx, telegramMsg))
+		assert.NoError(t, usecase.Forward(ctx, whatsappMsg))
+		assert.NoError(t, usecase.Forward(ctx, domain.Message{Key: "whatsapp-test:user-3"}))
+		assert.NoError(t, usecase.Forward(ctx, domain.Message{Key: "slack:user-4"}))
+		assert.NoError(t, usecase.Forward(ctx, domain.Message{Key: "slack:user-5"}))
+
+		mockForwardRepo.AssertExpectations(t)
+	})
+
+	t.Run("discarded messages are counted by origin", func(t *testing.T) {
+		mockForwardRepo := new(mocks.MockForwardRepository)
+		cfg := config.Config{OriginExclude: "counted-test", MetricsOrigins: []string{"counted-slack"}}
+		usecase, err := NewMessageService(cfg, mockForwardRepo, nil, nil, nil)
+		assert.NoError(t, err)
+		excluded := testutil.ToFloat64(metrics.MessagesFiltered.WithLabelValues("counted-test", "origin filter"))
+		noRoute := testutil.ToFloat64(metrics.MessagesFiltered.WithLabelValues("counted-slack", "no route"))
+
+		noRouteMsg := domain.Message{Key: "counted-slack:user-1"}
+		mockForwardRepo.On("Forward", mock.Anything, noRouteMsg).Return(domain.ErrNoRoute).Once()
+
+		assert.NoError(t, usecase.Forward(ctx, domain.Message{Key: "counted-test:user-1"}))
+		assert.NoError(t, usecase.Forward(ctx, domain.Message{Key: "counted-test:user-2"}))
+		assert.NoError(t, usecase.Forward(ctx, noRouteMsg))
+
+		assert.Equal(t, excluded+2, testutil.ToFloat64(metrics.MessagesFiltered.WithLabelValues("counted-test", "origin filter")))
+		assert.Equal(t, noRoute+1, testutil.ToFloat64(metrics.MessagesFiltered.WithLabelValues("counted-slack", "no route")))
+	})
+}
+
+func TestMessageUsecase_Forward_MessageFilter(t *testing.T) {
+	mockForwardRepo := new(mocks.MockForwardRepository)
+	messageFilter, err := filter.Compile(`headers.type == "text" && payload.chat.id != null`)
+	assert.NoError(t, err)
+	usecase, err := NewMessageService(config.Config{MetricsOrigins: []string{"telegram"}}, mockForwardRepo, nil, nil, messageFilter)
+	assert.NoError(t, err)
+	filtered := testutil.ToFloat64(metrics.MessagesFiltered.WithLabelValues("telegram", "expression filter"))
+
+	ctx := context.Background()
+	textMsg := domain.Message{
+		Key:     "telegram:user-1",
+		Headers: map[string]string{"type": "text"},
+		Content: []byte(`{"chat":{"id":1}}`),
+	}
+	mockForwardRepo.On("Forward", mock.Anything, textMsg).Return(nil).Once()
+
+	assert.NoError(t, usecase.Forward(ctx, textMsg))
+	assert.NoError(t, usecase.Forward(ctx, domain.Message{
+		Key:     "telegram:user-1",
+		Headers: map[string]string{"type": "typing"},
+		Content: []byte(`{"chat":{"id":1}}`),
+	}))
+
+	mockForwardRepo.AssertExpectations(t)
+	assert.Equal(t, filtered+1, testutil.ToFloat64(metrics.MessagesFiltered.WithLabelValues("telegram", "expression filter")))
+}
+
+func TestMessageUsecase_Forward_Metrics(t *testing.T) {
+	mockForwardRepo := new(mocks.MockForwardRepository)
+	usecase, err := NewMessageService(config.Config{
+		OriginExclude:  "metrics-test,metrics-un*",
+		MetricsOrigins: []string{"metrics-ok"},
+	}, mockForwardRepo, nil, nil, nil)
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	msg := domain.Message{Key: "metrics-ok:user-1", Timestamp: time.Now().Add(-time.Second)}
+	mockForwardRepo.On("Forward", mock.Anything, msg).Return(nil).Once()
+	forwarded := testutil.ToFloat64(metrics.MessagesForwarded.WithLabelValues("metrics-ok"))
+	filtered := testutil.ToFloat64(metrics.MessagesFiltered.WithLabelValues("metrics-test", "origin filter"))
+	filteredOther := testutil.ToFloat64(metrics.MessagesFiltered.WithLabelValues("other", "origin filter"))
+
+	assert.NoError(t, usecase.Forward(ctx, msg))
+	assert.NoError(t, usecase.Forward(ctx, domain.Message{Key: "metrics-test:user-1"}))
+	// origins that are not known are labelled as other
+	assert.NoError(t, usecase.Forward(ctx, domain.Message{Key: "metrics-unknown:user-1"}))
+
+	mockForwardRepo.AssertExpectations(t)
+	assert.Equal(t, forwarded+1, testutil.ToFloat64(metrics.MessagesForwarded.WithLabelValues("metrics-ok")))
+	assert.Equal(t, filtered+1, testutil.ToFloat64(metrics.MessagesFiltered.WithLabelValues("metrics-test", "origin filter")))
+	assert.Equal(t, filteredOther+1, testutil.ToFloat64(metrics.MessagesFiltered.WithLabelValues("other", "origin filter")))
+}
+
 func TestMessageUsecase_getOriginAndRoutingID(t *testing.T) {
 	usecase := &MessageUsecase{}
 
@@ -176,12 +395,19 @@ func TestMessageUsecase_getOriginAndRoutingID(t *testing.T) {
 	})
 }
 
+func TestMessageUsecase_NewMessageService_InvalidOrigin(t *testing.T) {
+	_, err := NewMessageService(config.Config{Origin: "telegram,[whats"}, nil, nil, nil, nil)
+
+	assert.ErrorContains(t, err, "invalid origin pattern [whats")
+}
+
 func TestMessageUsecase_NewMessageService(t *testing.T) {
 	mockForwardRepo := new(mocks.MockForwardRepository)
 	mockConsumerRepo := new(mocks.MockConsumerRepository)
 	cfg := config.Config{Origin: "test-origin"}
 
-	usecase := NewMessageService(cfg, mockForwardRepo, mockConsumerRepo)
+	usecase, err := NewMessageService(cfg, mockForwardRepo, mockConsumerRepo, nil, nil)
+	assert.NoError(t, err)
 
 	assert.NotNil(t, usecase)
 
@@ -201,7 +427,8 @@ func TestMessageUsecase_NewMessageService(t *testing.T) {
 func TestMessageUsecase_Consume(t *testing.T) {
 	mockConsumerRepo := new(mocks.MockConsumerRepository)
 	cfg := config.Config{} // or initialize with specific values if necessary
-	usecase := NewMessageService(cfg, nil, mockConsumerRepo)
+	usecase, err := NewMessageService(cfg, nil, mockConsumerRepo, nil, nil)
+	assert.NoError(t, err)
 
 	ctx := context.Background()
 
@@ -249,10 +476,68 @@ func TestMessageUsecase_Consume(t *testing.T) {
 	})
 }
 
+func TestMessageUsecase_Commit(t *testing.T) {
+	mockConsumerRepo := new(mocks.MockConsumerRepository)
+	usecase, err := NewMessageService(config.Config{}, nil, mockConsumerRepo, nil, nil)
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	msg := domain.Message{Topic: "test-topic", Partition: 1, Offset: 10}
+
+	t.Run("success", func(t *testing.T) {
+		mockConsumerRepo.On("Commit", ctx, msg).Return(nil).Once()
+
+		err := usecase.Commit(ctx, msg)
+
+		assert.NoError(t, err)
+		mockConsumerRepo.AssertExpectations(t)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		expectedErr := errors.New("commit error")
+		mockConsumerRepo.On("Commit", ctx, msg).Return(expectedErr).Once()
+
+		err := usecase.Commit(ctx, msg)
+
+		assert.Equal(t, expectedErr, err)
+		mockConsumerRepo.AssertExpectations(t)
+	})
+}
+
+func TestMessageUsecase_Reject(t *testing.T) {
+	ctx := context.Background()
+	msg := domain.Message{Topic: "test-queue", Offset: 10}
+
+	t.Run("consumer without reject", func(t *testing.T) {
+		usecase, err := NewMessageService(config.Config{}, nil, new(mocks.MockConsumerRepository), nil, nil)
+		assert.NoError(t, err)
+
+		assert.NoError(t, usecase.(domain.Rejecter).Reject(ctx, msg))
+	})
+
+	t.Run("consumer with reject", func(t *testing.T) {
+		rejecter := new(mocks.MockRejecter)
+		consumer := struct {
+			*mocks.MockConsumerRepository
+			*mocks.MockRejecter
+		}{new(mocks.MockConsumerRepository), rejecter}
+		usecase, err := NewMessageService(config.Config{}, nil, consumer, nil, nil)
+		assert.NoError(t, err)
+		expectedErr := errors.New("reject error")
+		rejecter.On("Reject", ctx, msg).Return(expectedErr).Once()
+
+		err = usecase.(domain.Rejecter).Reject(ctx, msg)
+
+		assert.Equal(t, expectedErr, err)
+		rejecter.AssertExpectations(t)
+	})
+}
+
 func TestMessageUsecase_Close(t *testing.T) {
 	mockConsumerRepo := new(mocks.MockConsumerRepository)
 	cfg := config.Config{} // or initialize with specific values if necessary
-	usecase := NewMessageService(cfg, nil, mockConsumerRepo)
+	usecase, err := NewMessageService(cfg, nil, mockConsumerRepo, nil, nil)
+	assert.NoError(t, err)
 
 	t.Run("success", func(t *testing.T) {
 		mockConsumerRepo.On("Close").Return(nil).Once()
@@ -275,13 +560,31 @@ func TestMessageUsecase_Close(t *testing.T) {
 	})
 }
 
+func TestMessageUsecase_Close_DeadLetter(t *testing.T) {
+	mockConsumerRepo := new(mocks.MockConsumerRepository)
+	mockDeadLetterRepo := new(mocks.MockDeadLetterRepository)
+	usecase, err := NewMessageService(config.Config{}, nil, mockConsumerRepo, mockDeadLetterRepo, nil)
+	assert.NoError(t, err)
+
+	closeErr := errors.New("close error")
+	mockConsumerRepo.On("Close").Return(nil).Once()
+	mockDeadLetterRepo.On("Close").Return(closeErr).Once()
+
+	err = usecase.Close()
+
+	assert.ErrorIs(t, err, closeErr)
+	mockConsumerRepo.AssertExpectations(t)
+	mockDeadLetterRepo.AssertExpectations(t)
+}
+
 // Integration test that verifies the complete flow
 func TestMessageUsecase_Integration(t *testing.T) {
 	mockForwardRepo := new(mocks.MockForwardRepository)
 	mockConsumerRepo := new(mocks.MockConsumerRepository)
 	cfg := config.Config{Origin: "test-service"}
 
-	usecase := NewMessageService(cfg, mockForwardRepo, mockConsumerRepo)
+	usecase, err := NewMessageService(cfg, mockForwardRepo, mockConsumerRepo, nil, nil)
+	assert.NoError(t, err)
 	ctx := context.Background()
 
 	t.Run("complete workflow", func(t *testing.T) {
@@ -296,7 +599,7 @@ func TestMessageUsecase_Integration(t *testing.T) {
 			Key:     "test-service:user-123",
 			Content: []byte("test content"),
 		}
-		mockForwardRepo.On("Forward", ctx, msg).Return(nil)
+		mockForwardRepo.On("Forward", mock.Anything, msg).Return(nil)
 
 		// Mock close
 		mockConsumerRepo.On("Close").Return(nil)
@@ -342,7 +645,8 @@ func BenchmarkMessageUsecase_getOriginAndRoutingID(b *testing.B) {
 func BenchmarkMessageUsecase_Forward(b *testing.B) {
 	mockForwardRepo := new(mocks.MockForwardRepository)
 	cfg := config.Config{Origin: ""}
-	usecase := NewMessageService(cfg, mockForwardRepo, nil)
+	usecase, err := NewMessageService(cfg, mockForwardRepo, nil, nil, nil)
+	assert.NoError(b, err)
 
 	ctx := context.Background()
 	msg := domain.Message{
@@ -350,10 +654,92 @@ func BenchmarkMessageUsecase_Forward(b *testing.B) {
 		Content: []byte("benchmark message"),
 	}
 
-	mockForwardRepo.On("Forward", ctx, msg).Return(nil)
+	mockForwardRepo.On("Forward", mock.Anything, msg).Return(nil)
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		_ = usecase.Forward(ctx, msg)
 	}
 }
+
+// recordSpans records the spans ended during the test, and propagates the W3C trace context.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+func TestMessageUsecase_Forward_Tracing(t *testing.T) {
+	mockForwardRepo := new(mocks.MockForwardRepository)
+	usecase, err := NewMessageService(config.Config{OriginExclude: "test"}, mockForwardRepo, nil, nil, nil)
+	assert.NoError(t, err)
+	headers := map[string]string{"traceparent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"}
+
+	t.Run("forwarded", func(t *testing.T) {
+		recorder := recordSpans(t)
+		msg := domain.Message{Key: "telegram:123", Topic: "orders", Headers: headers}
+		forwardErr := errors.New("forward error")
+		mockForwardRepo.On("Forward", mock.MatchedBy(func(ctx context.Context) bool {
+			// the repository continues the trace of the forward span
+			return trace.SpanContextFromContext(ctx).TraceID().String() == "0af7651916cd43dd8448eb211c80319c"
+		}), msg).Return(forwardErr).Once()
+
+		assert.ErrorIs(t, usecase.Forward(context.Background(), msg), forwardErr)
+
+		spans := recorder.Ended()
+		assert.Len(t, spans, 2)
+		filterSpan, forwardSpan := spans[0], spans[1]
+		assert.Equal(t, "forward orders", forwardSpan.Name())
+		assert.Equal(t, "b7ad6b7169203331", forwardSpan.Parent().SpanID().String())
+		assert.Equal(t, codes.Error, forwardSpan.Status().Code)
+		assert.Equal(t, "filter", filterSpan.Name())
+		assert.Equal(t, forwardSpan.SpanContext().SpanID(), filterSpan.Parent().SpanID())
+		assert.Contains(t, forwardSpan.Attributes(), semconv.MessagingSystemKafka)
+		assert.Contains(t, filterSpan.Attributes(), attribute.Bool("anyker.filter.discarded", false))
+		mockForwardRepo.AssertExpectations(t)
+	})
+
+	t.Run("messaging system of the source", func(t *testing.T) {
+		tests := []struct {
+			sourceType string
+			expected   attribute.KeyValue
+		}{
+			{sourceType: "kafka", expected: semconv.MessagingSystemKafka},
+			{sourceType: "amqp", expected: semconv.MessagingSystemRabbitmq},
+			{sourceType: "nats", expected: semconv.MessagingSystemKey.String("nats")},
+			{sourceType: "Redis", expected: semconv.MessagingSystemKey.String("redis")},
+		}
+		for _, tt := range tests {
+			recorder := recordSpans(t)
+			usecase, err := NewMessageService(config.Config{SourceType: tt.sourceType, OriginExclude: "test"}, nil, nil, nil, nil)
+			assert.NoError(t, err)
+
+			assert.NoError(t, usecase.Forward(context.Background(), domain.Message{Key: "test:123"}))
+
+			spans := recorder.Ended()
+			if assert.Len(t, spans, 2) {
+				assert.Contains(t, spans[1].Attributes(), tt.expected, tt.sourceType)
+			}
+		}
+	})
+
+	t.Run("discarded", func(t *testing.T) {
+		recorder := recordSpans(t)
+		msg := domain.Message{Key: "test:123", Headers: headers}
+
+		assert.NoError(t, usecase.Forward(context.Background(), msg))
+
+		spans := recorder.Ended()
+		assert.Len(t, spans, 2)
+		assert.Equal(t, "filter", spans[0].Name())
+		assert.Contains(t, spans[0].Attributes(), attribute.Bool("anyker.filter.discarded", true))
+		assert.Contains(t, spans[0].Attributes(), attribute.String("anyker.filter.reason", "origin filter"))
+		assert.Equal(t, codes.Unset, spans[1].Status().Code)
+	})
+}
@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNoRoute is returned when there is no destination configured for the origin of a message.
+var ErrNoRoute = errors.New("no route for message origin")
+
+// ForwardError describes a message that could not be forwarded to the downstream service.
+type ForwardError struct {
+	// StatusCode is the last HTTP status code received, or 0 if no response was received.
+	StatusCode int
+	// ClientError reports that the downstream service rejected the message, so it is up,
+	// for the failures without HTTP status code, e.g. a gRPC InvalidArgument status.
+	ClientError bool
+	// Attempts is the number of forward attempts made before giving up.
+	Attempts int
+	// Err is the error returned by the last attempt.
+	Err error
+}
+
+// Error returns the error message of the last attempt along with the number of attempts.
+func (e *ForwardError) Error() string {
+	return fmt.Sprintf("forward failed after %d attempt(s): %v", e.Attempts, e.Err)
+}
+
+// Unwrap returns the error of the last attempt.
+func (e *ForwardError) Unwrap() error {
+	return e.Err
+}
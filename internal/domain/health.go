@@ -0,0 +1,9 @@
+package domain
+
+// HealthChecker is implemented by the components that report their state to the health endpoints.
+type HealthChecker interface {
+	// Live returns an error when the component is wedged and the process should be restarted.
+	Live() error
+	// Ready returns an error when the component is not ready to process messages.
+	Ready() error
+}
@@ -1,8 +1,69 @@
 package domain
 
+import (
+	"strings"
+	"time"
+)
+
+// CorrelationIDHeader is the message header carrying the correlation ID.
+const CorrelationIDHeader = "correlation_id"
+
 // Message represents a message consumed from Kafka.
 type Message struct {
 	Content []byte
 	Headers map[string]string
 	Key     string
+
+	// CorrelationID identifies the message across services, it is the correlation_id header
+	// or an ID generated by anyker when the header is missing.
+	CorrelationID string
+
+	// Topic, Partition and Offset identify the message in Kafka, so it can be committed once processed.
+	// Topic is the topic the message was consumed from, which matters when subscribed to several topics.
+	// Messages consumed from an AMQP queue have the queue as Topic and the delivery tag as Offset.
+	// Messages consumed from NATS JetStream have the stream as Topic, the subject ending with the key, and the stream sequence as Offset.
+	Topic     string
+	Partition int32
+	Offset    int64
+	// ID identifies the message in the sources whose positions are not numbers,
+	// e.g. the entry ID of a message consumed from a Redis stream, which has the stream as Topic.
+	ID string
+
+	// Timestamp is the time the message was produced, or appended to the log, depending on the topic configuration.
+	Timestamp time.Time
+}
+
+// ParseKey extracts the origin and routing ID from a message key with the format origin:routingId.
+// If the key does not contain a colon, the entire key is treated as the origin, and routingId remains empty.
+func ParseKey(key string) (origin string, routingId string) {
+	keyParts := strings.SplitN(key, ":", 2)
+
+	if len(keyParts) == 2 {
+		origin = keyParts[0]
+		routingId = keyParts[1]
+	} else {
+		// the entire key is taken as origin, userID remains empty
+		origin = keyParts[0]
+	}
+	return
+}
+
+// Reply represents the response of the API to a forwarded message, published back to Kafka in request/reply mode.
+type Reply struct {
+	Message     Message
+	StatusCode  int
+	ContentType string
+	Content     []byte
+	NanobotName string
+	Timestamp   time.Time
+}
+
+// DeadLetter represents a message that permanently failed to be forwarded, along with the failure metadata.
+type DeadLetter struct {
+	Message     Message
+	Error       string
+	StatusCode  int
+	Attempts    int
+	NanobotName string
+	Timestamp   time.Time
 }
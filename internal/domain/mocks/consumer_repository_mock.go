@@ -15,6 +15,11 @@ func (m *MockConsumerRepository) Consume(ctx context.Context, messages chan<- *d
 	return args.Error(0)
 }
 
+func (m *MockConsumerRepository) Commit(ctx context.Context, message domain.Message) error {
+	args := m.Called(ctx, message)
+	return args.Error(0)
+}
+
 func (m *MockConsumerRepository) Close() error {
 	args := m.Called()
 	return args.Error(0)
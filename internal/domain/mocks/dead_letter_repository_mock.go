@@ -0,0 +1,21 @@
+package mocks
+
+import (
+	"anyker/internal/domain"
+	"context"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockDeadLetterRepository struct {
+	mock.Mock
+}
+
+func (m *MockDeadLetterRepository) Publish(ctx context.Context, deadLetter domain.DeadLetter) error {
+	args := m.Called(ctx, deadLetter)
+	return args.Error(0)
+}
+
+func (m *MockDeadLetterRepository) Close() error {
+	args := m.Called()
+	return args.Error(0)
+}
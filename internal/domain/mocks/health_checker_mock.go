@@ -0,0 +1,19 @@
+package mocks
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+type MockHealthChecker struct {
+	mock.Mock
+}
+
+func (m *MockHealthChecker) Live() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockHealthChecker) Ready() error {
+	args := m.Called()
+	return args.Error(0)
+}
@@ -0,0 +1,17 @@
+package mocks
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPauser struct {
+	mock.Mock
+}
+
+func (m *MockPauser) Pause() {
+	m.Called()
+}
+
+func (m *MockPauser) Resume() {
+	m.Called()
+}
@@ -0,0 +1,17 @@
+package mocks
+
+import (
+	"anyker/internal/domain"
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRejecter struct {
+	mock.Mock
+}
+
+func (m *MockRejecter) Reject(ctx context.Context, message domain.Message) error {
+	args := m.Called(ctx, message)
+	return args.Error(0)
+}
@@ -0,0 +1,21 @@
+package mocks
+
+import (
+	"anyker/internal/domain"
+	"context"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockReplyRepository struct {
+	mock.Mock
+}
+
+func (m *MockReplyRepository) Publish(ctx context.Context, reply domain.Reply) error {
+	args := m.Called(ctx, reply)
+	return args.Error(0)
+}
+
+func (m *MockReplyRepository) Close() error {
+	args := m.Called()
+	return args.Error(0)
+}
@@ -7,12 +7,45 @@ type ConsumerRepository interface {
 	// Consume starts consuming messages and sends them to the provided channel.
 	// This method should block until the context is cancelled or an error occurs.
 	Consume(ctx context.Context, messages chan<- *Message) error
+	// Commit marks a message as processed, so it is not consumed again after a restart or rebalance.
+	Commit(ctx context.Context, message Message) error
 	// Close closes the consumer connection.
 	Close() error
 }
 
+// Pauser is implemented by the consumers that can stop fetching messages for a while without leaving the group.
+type Pauser interface {
+	// Pause stops fetching new messages until Resume is called.
+	Pause()
+	// Resume fetches messages again after a Pause.
+	Resume()
+}
+
+// Rejecter is implemented by the consumers that must be told when a message could not be processed,
+// e.g. to give it back to the broker instead of waiting for a restart to consume it again.
+type Rejecter interface {
+	// Reject gives back a message that could not be processed.
+	Reject(ctx context.Context, message Message) error
+}
+
 // ForwardRepository defines the interface for forwarding messages.
 type ForwardRepository interface {
 	// Forward forwards a message to a downstream service.
 	Forward(ctx context.Context, message Message) error
 }
+
+// ReplyRepository defines the interface for publishing the responses of the API to forwarded messages.
+type ReplyRepository interface {
+	// Publish publishes a reply and waits until it has been delivered.
+	Publish(ctx context.Context, reply Reply) error
+	// Close flushes pending replies and closes the producer connection.
+	Close() error
+}
+
+// DeadLetterRepository defines the interface for publishing messages that could not be forwarded.
+type DeadLetterRepository interface {
+	// Publish publishes a dead letter and waits until it has been delivered.
+	Publish(ctx context.Context, deadLetter DeadLetter) error
+	// Close flushes pending dead letters and closes the producer connection.
+	Close() error
+}
@@ -7,9 +7,12 @@ type MessageUseCase interface {
 	// Forward forwards a message to a downstream service.
 	Forward(ctx context.Context, message Message) error
 
-	// Consume consumes messages from Kafka and sends them to the provided channel.
+	// Consume consumes messages from the source and sends them to the provided channel.
 	Consume(ctx context.Context, messages chan<- *Message) error
 
+	// Commit marks a message as processed once it has been forwarded.
+	Commit(ctx context.Context, message Message) error
+
 	// Close closes the use case and its underlying resources.
 	Close() error
 }
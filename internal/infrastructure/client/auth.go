@@ -0,0 +1,203 @@
+package client
+
+import (
+	"anyker/config"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// Authentication types supported by NewAuthenticator.
+const (
+	AuthNone   = "none"
+	AuthBearer = "bearer"
+	AuthBasic  = "basic"
+	AuthAPIKey = "api_key"
+	AuthOAuth2 = "oauth2"
+)
+
+// Authenticator adds the credentials to the requests made by HttpClientImpl.
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+// NewAuthenticator creates the Authenticator selected by the auth configuration.
+// It returns nil for AuthNone, so requests are sent without credentials.
+func NewAuthenticator(config config.Config) (Authenticator, error) {
+	switch strings.ToLower(config.AuthType) {
+	case "", AuthNone:
+		return nil, nil
+	case AuthBearer:
+		if config.AuthBearerTokenFile != "" {
+			return NewBearerTokenFile(config.AuthBearerTokenFile), nil
+		}
+		if config.AuthBearerToken == "" {
+			return nil, errors.New("bearer auth requires a token or a token file")
+		}
+		return NewBearerToken(config.AuthBearerToken), nil
+	case AuthBasic:
+		if config.AuthUsername == "" {
+			return nil, errors.New("basic auth requires a username")
+		}
+		return NewBasicAuth(config.AuthUsername, config.AuthPassword), nil
+	case AuthAPIKey:
+		if config.AuthAPIKey == "" {
+			return nil, errors.New("API key auth requires a key")
+		}
+		return NewAPIKey(config.AuthAPIKeyHeader, config.AuthAPIKey), nil
+	case AuthOAuth2:
+		if config.OAuth2TokenURL == "" || config.OAuth2ClientID == "" {
+			return nil, errors.New("OAuth2 auth requires a token URL and a client ID")
+		}
+		return NewOAuth2ClientCredentials(&clientcredentials.Config{
+			ClientID:       config.OAuth2ClientID,
+			ClientSecret:   config.OAuth2ClientSecret,
+			TokenURL:       config.OAuth2TokenURL,
+			Scopes:         config.OAuth2Scopes,
+			EndpointParams: oauth2EndpointParams(config.OAuth2Audience),
+		}, &http.Client{Timeout: config.HTTPClientTimeout}), nil
+	default:
+		return nil, fmt.Errorf("unknown auth type: %s", config.AuthType)
+	}
+}
+
+// BearerToken authenticates requests with a static bearer token.
+type BearerToken struct {
+	token string
+}
+
+// NewBearerToken creates a new BearerToken.
+func NewBearerToken(token string) *BearerToken {
+	return &BearerToken{token: token}
+}
+
+// Authenticate sets the Authorization header.
+func (b *BearerToken) Authenticate(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+b.token)
+	return nil
+}
+
+// BearerTokenFile authenticates requests with a bearer token read from a file.
+// The file is read again when it changes, so mounted secrets can be rotated without restarting.
+type BearerTokenFile struct {
+	path string
+
+	mu      sync.Mutex
+	token   string
+	modTime time.Time
+}
+
+// NewBearerTokenFile creates a new BearerTokenFile, the file is read on the first request.
+func NewBearerTokenFile(path string) *BearerTokenFile {
+	return &BearerTokenFile{path: path}
+}
+
+// Authenticate sets the Authorization header with the current content of the file.
+func (b *BearerTokenFile) Authenticate(req *http.Request) error {
+	token, err := b.read()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// read returns the token, reading the file again if it was modified since the last read.
+func (b *BearerTokenFile) read() (string, error) {
+	info, err := os.Stat(b.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read bearer token file: %w", err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.token != "" && info.ModTime().Equal(b.modTime) {
+		return b.token, nil
+	}
+	content, err := os.ReadFile(b.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read bearer token file: %w", err)
+	}
+	token := strings.TrimSpace(string(content))
+	if token == "" {
+		return "", fmt.Errorf("bearer token file %s is empty", b.path)
+	}
+	b.token = token
+	b.modTime = info.ModTime()
+	return b.token, nil
+}
+
+// BasicAuth authenticates requests with HTTP basic authentication.
+type BasicAuth struct {
+	username string
+	password string
+}
+
+// NewBasicAuth creates a new BasicAuth.
+func NewBasicAuth(username, password string) *BasicAuth {
+	return &BasicAuth{username: username, password: password}
+}
+
+// Authenticate sets the Authorization header.
+func (b *BasicAuth) Authenticate(req *http.Request) error {
+	req.SetBasicAuth(b.username, b.password)
+	return nil
+}
+
+// APIKey authenticates requests with an API key sent in a header.
+type APIKey struct {
+	header string
+	key    string
+}
+
+// NewAPIKey creates a new APIKey sent in the given header, X-API-Key if empty.
+func NewAPIKey(header, key string) *APIKey {
+	if header == "" {
+		header = "X-API-Key"
+	}
+	return &APIKey{header: header, key: key}
+}
+
+// Authenticate sets the API key header.
+func (a *APIKey) Authenticate(req *http.Request) error {
+	req.Header.Set(a.header, a.key)
+	return nil
+}
+
+// OAuth2ClientCredentials authenticates requests with an access token obtained with the OAuth2 client credentials grant.
+// The token is cached and fetched again shortly before it expires.
+type OAuth2ClientCredentials struct {
+	tokenSource oauth2.TokenSource
+}
+
+// NewOAuth2ClientCredentials creates a new OAuth2ClientCredentials, tokens are fetched with the given HTTP client.
+func NewOAuth2ClientCredentials(config *clientcredentials.Config, httpClient *http.Client) *OAuth2ClientCredentials {
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, httpClient)
+	return &OAuth2ClientCredentials{tokenSource: config.TokenSource(ctx)}
+}
+
+// Authenticate sets the Authorization header with a valid access token.
+func (o *OAuth2ClientCredentials) Authenticate(req *http.Request) error {
+	token, err := o.tokenSource.Token()
+	if err != nil {
+		return fmt.Errorf("failed to get OAuth2 token: %w", err)
+	}
+	token.SetAuthHeader(req)
+	return nil
+}
+
+// oauth2EndpointParams returns the extra parameters of the token request.
+func oauth2EndpointParams(audience string) map[string][]string {
+	if audience == "" {
+		return nil
+	}
+	return map[string][]string{"audience": {audience}}
+}
@@ -0,0 +1,159 @@
+package client
+
+import (
+	"anyker/config"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAuthenticator(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      config.Config
+		expected    interface{}
+		expectedErr string
+	}{
+		{name: "none", config: config.Config{AuthType: "none"}, expected: nil},
+		{name: "empty", config: config.Config{}, expected: nil},
+		{name: "bearer", config: config.Config{AuthType: "bearer", AuthBearerToken: "token"}, expected: &BearerToken{}},
+		{name: "bearer file", config: config.Config{AuthType: "BEARER", AuthBearerTokenFile: "/run/secrets/token"}, expected: &BearerTokenFile{}},
+		{name: "bearer without token", config: config.Config{AuthType: "bearer"}, expectedErr: "bearer auth requires a token or a token file"},
+		{name: "basic", config: config.Config{AuthType: "basic", AuthUsername: "user"}, expected: &BasicAuth{}},
+		{name: "basic without username", config: config.Config{AuthType: "basic"}, expectedErr: "basic auth requires a username"},
+		{name: "api key", config: config.Config{AuthType: "api_key", AuthAPIKey: "key"}, expected: &APIKey{}},
+		{name: "api key without key", config: config.Config{AuthType: "api_key"}, expectedErr: "API key auth requires a key"},
+		{
+			name:     "oauth2",
+			config:   config.Config{AuthType: "oauth2", OAuth2TokenURL: "http://localhost/token", OAuth2ClientID: "anyker"},
+			expected: &OAuth2ClientCredentials{},
+		},
+		{name: "oauth2 without token URL", config: config.Config{AuthType: "oauth2"}, expectedErr: "OAuth2 auth requires a token URL and a client ID"},
+		{name: "unknown", config: config.Config{AuthType: "kerberos"}, expectedErr: "unknown auth type: kerberos"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authenticator, err := NewAuthenticator(tt.config)
+
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			if tt.expected == nil {
+				assert.Nil(t, authenticator)
+			} else {
+				assert.IsType(t, tt.expected, authenticator)
+			}
+		})
+	}
+}
+
+func TestAuthenticators(t *testing.T) {
+	newRequest := func() *http.Request {
+		return httptest.NewRequest(http.MethodPost, "http://localhost/messages", nil)
+	}
+
+	t.Run("bearer token", func(t *testing.T) {
+		req := newRequest()
+		assert.NoError(t, NewBearerToken("secret").Authenticate(req))
+		assert.Equal(t, "Bearer secret", req.Header.Get("Authorization"))
+	})
+
+	t.Run("basic auth", func(t *testing.T) {
+		req := newRequest()
+		assert.NoError(t, NewBasicAuth("user", "pass").Authenticate(req))
+		username, password, ok := req.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "user", username)
+		assert.Equal(t, "pass", password)
+	})
+
+	t.Run("api key", func(t *testing.T) {
+		req := newRequest()
+		assert.NoError(t, NewAPIKey("", "key").Authenticate(req))
+		assert.Equal(t, "key", req.Header.Get("X-API-Key"))
+
+		req = newRequest()
+		assert.NoError(t, NewAPIKey("X-Token", "key").Authenticate(req))
+		assert.Equal(t, "key", req.Header.Get("X-Token"))
+	})
+}
+
+func TestBearerTokenFile_Authenticate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	assert.NoError(t, os.WriteFile(path, []byte("first\n"), 0o600))
+	authenticator := NewBearerTokenFile(path)
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/messages", nil)
+	assert.NoError(t, authenticator.Authenticate(req))
+	assert.Equal(t, "Bearer first", req.Header.Get("Authorization"))
+
+	// rotated secret
+	assert.NoError(t, os.WriteFile(path, []byte("second"), 0o600))
+	assert.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+
+	req = httptest.NewRequest(http.MethodPost, "http://localhost/messages", nil)
+	assert.NoError(t, authenticator.Authenticate(req))
+	assert.Equal(t, "Bearer second", req.Header.Get("Authorization"))
+
+	assert.NoError(t, os.WriteFile(path, nil, 0o600))
+	assert.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(2*time.Minute)))
+	assert.ErrorContains(t, authenticator.Authenticate(req), "is empty")
+}
+
+func TestOAuth2ClientCredentials_Authenticate(t *testing.T) {
+	var tokenRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests.Add(1)
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.Form.Get("grant_type"))
+		assert.Equal(t, "messages:write", r.Form.Get("scope"))
+		assert.Equal(t, "https://bots.example.com", r.Form.Get("audience"))
+		clientID, clientSecret, _ := r.BasicAuth()
+		assert.Equal(t, "anyker", clientID)
+		assert.Equal(t, "secret", clientSecret)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"access-token","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	authenticator, err := NewAuthenticator(config.Config{
+		AuthType:           "oauth2",
+		OAuth2TokenURL:     server.URL,
+		OAuth2ClientID:     "anyker",
+		OAuth2ClientSecret: "secret",
+		OAuth2Scopes:       []string{"messages:write"},
+		OAuth2Audience:     "https://bots.example.com",
+	})
+	assert.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "http://localhost/messages", nil)
+		assert.NoError(t, authenticator.Authenticate(req))
+		assert.Equal(t, "Bearer access-token", req.Header.Get("Authorization"))
+	}
+	// the token is cached until it expires
+	assert.Equal(t, int32(1), tokenRequests.Load())
+}
+
+func TestOAuth2ClientCredentials_Authenticate_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	authenticator, err := NewAuthenticator(config.Config{AuthType: "oauth2", OAuth2TokenURL: server.URL, OAuth2ClientID: "anyker"})
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/messages", nil)
+	assert.ErrorContains(t, authenticator.Authenticate(req), "failed to get OAuth2 token")
+}
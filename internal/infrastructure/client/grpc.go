@@ -0,0 +1,87 @@
+package client
+
+import (
+	"anyker/config"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// NewGRPCConn creates the gRPC connection used to forward messages to the configured target, over TLS with the
+// HTTP TLS configuration unless GRPCInsecure is set. The calls are traced, with the trace context sent in the
+// traceparent metadata, and authenticated by the authenticator if any. The connection is established lazily.
+func NewGRPCConn(config config.Config, authenticator Authenticator) (*grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+	if !config.GRPCInsecure {
+		tlsConfig, err := newTLSConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		creds = credentials.NewTLS(tlsConfig)
+	}
+
+	interceptors := []grpc.UnaryClientInterceptor{traceUnary}
+	if authenticator != nil {
+		interceptors = append(interceptors, authenticateUnary(authenticator))
+	}
+	conn, err := grpc.NewClient(config.GRPCTarget,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithChainUnaryInterceptor(interceptors...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC connection: %w", err)
+	}
+	return conn, nil
+}
+
+// traceUnary records the span of a call and sends its trace context in the metadata.
+func traceUnary(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	service, name, _ := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	ctx, span := otel.Tracer(tracerName).Start(ctx, service+"/"+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.RPCSystemGRPC, semconv.RPCService(service), semconv.RPCMethod(name)))
+	defer span.End()
+
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	for key, value := range carrier {
+		ctx = metadata.AppendToOutgoingContext(ctx, key, value)
+	}
+
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(status.Code(err))))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// authenticateUnary adds the credentials of the authenticator to the metadata of the calls.
+// The authenticators set HTTP headers, they are sent as metadata with the same names, in lower case.
+func authenticateUnary(authenticator Authenticator) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, method, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		if err := authenticator.Authenticate(httpReq); err != nil {
+			return fmt.Errorf("failed to authenticate request: %w", err)
+		}
+		for key, values := range httpReq.Header {
+			ctx = metadata.AppendToOutgoingContext(ctx, strings.ToLower(key), strings.Join(values, ","))
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
@@ -0,0 +1,87 @@
+package client
+
+import (
+	forwardv1 "anyker/api/forward/v1"
+	"anyker/config"
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type unavailableForwarder struct {
+	forwardv1.UnimplementedForwarderServer
+}
+
+func (unavailableForwarder) Forward(context.Context, *forwardv1.ForwardRequest) (*forwardv1.ForwardResponse, error) {
+	return nil, status.Error(grpccodes.Unavailable, "restarting")
+}
+
+// startGRPCServer serves a Forwarder service failing every call and returns its address
+// and the metadata of the last call.
+func startGRPCServer(t *testing.T) (string, *metadata.MD) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	var md metadata.MD
+	server := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ = metadata.FromIncomingContext(ctx)
+		return handler(ctx, req)
+	}))
+	forwardv1.RegisterForwarderServer(server, unavailableForwarder{})
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return listener.Addr().String(), &md
+}
+
+func TestNewGRPCConn(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	}()
+
+	target, md := startGRPCServer(t)
+	conn, err := NewGRPCConn(config.Config{GRPCTarget: target, GRPCInsecure: true}, NewAPIKey("X-API-Key", "secret"))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx := propagation.TraceContext{}.Extract(context.Background(),
+		propagation.MapCarrier{"traceparent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"})
+	_, err = forwardv1.NewForwarderClient(conn).Forward(ctx, &forwardv1.ForwardRequest{})
+	assert.Equal(t, grpccodes.Unavailable, status.Code(err))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "anyker.forward.v1.Forwarder/Forward", span.Name())
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", span.SpanContext().TraceID().String())
+	assert.Equal(t, "b7ad6b7169203331", span.Parent().SpanID().String())
+	assert.Equal(t, codes.Error, span.Status().Code)
+	assert.Contains(t, span.Attributes(), semconv.RPCMethod("Forward"))
+	assert.Contains(t, span.Attributes(), semconv.RPCGRPCStatusCodeKey.Int(int(grpccodes.Unavailable)))
+	// the service receives the context of the call span and the credentials
+	assert.Equal(t, []string{"00-0af7651916cd43dd8448eb211c80319c-" + span.SpanContext().SpanID().String() + "-01"}, md.Get("traceparent"))
+	assert.Equal(t, []string{"secret"}, md.Get("x-api-key"))
+}
+
+func TestNewGRPCConn_Errors(t *testing.T) {
+	_, err := NewGRPCConn(config.Config{GRPCTarget: "localhost:50051", HTTPTLSMinVersion: "2.0"}, nil)
+
+	assert.EqualError(t, err, "unsupported minimum TLS version: 2.0")
+}
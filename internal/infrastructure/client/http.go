@@ -7,12 +7,23 @@ import (
 	"fmt"
 	"github.com/rs/zerolog/log"
 	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// tracerName is the name of the tracer of the HTTP client.
+const tracerName = "anyker/internal/infrastructure/client"
+
 // HttpClientImpl implements HttpClient interface for making HTTP requests
 type HttpClientImpl struct {
-	client      *http.Client
-	bearerToken string
+	client        *http.Client
+	authenticator Authenticator
+	signer        *Signer
 }
 
 // HttpClient defines the interface for making HTTP requests
@@ -20,29 +31,49 @@ type HttpClient interface {
 	Post(ctx context.Context, headers map[string]string, payload interface{}, url string) (*http.Response, error)
 }
 
-// NewHttpClient creates a new HTTP client with bearer token authentication
-func NewHttpClient(client *http.Client, bearerToken string) HttpClient {
+// NewHttpClient creates a new HTTP client authenticating its requests with the given authenticator,
+// and signing them with the given signer. Both are optional, when nil requests are sent without credentials
+// or without signature.
+func NewHttpClient(client *http.Client, authenticator Authenticator, signer *Signer) HttpClient {
 	return &HttpClientImpl{
-		client:      client,
-		bearerToken: bearerToken,
+		client:        client,
+		authenticator: authenticator,
+		signer:        signer,
 	}
 }
 
-// Post sends a POST request with JSON payload and bearer token authentication
-func (c *HttpClientImpl) Post(ctx context.Context, headers map[string]string, payload interface{}, url string) (*http.Response, error) {
+// Post sends a POST request with JSON payload, authenticated by the authenticator and signed by the signer if any.
+// The request is traced, and the trace context is sent in the traceparent header.
+func (c *HttpClientImpl) Post(ctx context.Context, headers map[string]string, payload interface{}, url string) (resp *http.Response, err error) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "POST",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.HTTPRequestMethodPost, semconv.URLFull(url)))
+	defer func() {
+		if resp != nil {
+			span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+			if resp.StatusCode >= 400 {
+				span.SetStatus(codes.Error, resp.Status)
+			}
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
 	var jsonPayload []byte
 	switch v := payload.(type) {
 	case []byte:
 		jsonPayload = v
 	default:
-		var err error
 		jsonPayload, err = json.Marshal(v)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal payload: %w", err)
 		}
 	}
-	log.Debug().Msgf("payload to send: %s", string(jsonPayload))
-	log.Debug().Msgf("url %s", url)
+	log.Ctx(ctx).Debug().Msgf("payload to send: %s", string(jsonPayload))
+	log.Ctx(ctx).Debug().Msgf("url %s", url)
 
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonPayload))
 	if err != nil {
@@ -52,13 +83,23 @@ func (c *HttpClientImpl) Post(ctx context.Context, headers map[string]string, pa
 		// Set headers
 		req.Header.Set(key, value)
 	}
-	log.Debug().Msgf("headers: to send to %s %+v", url, req.Header)
+	log.Ctx(ctx).Debug().Msgf("headers: to send to %s %+v", url, req.Header)
 
-	req.Header.Set("Authorization", "Bearer "+c.bearerToken)
+	if c.authenticator != nil {
+		if err := c.authenticator.Authenticate(req); err != nil {
+			return nil, fmt.Errorf("failed to authenticate request: %w", err)
+		}
+	}
+	if c.signer != nil {
+		for key, value := range c.signer.Headers(jsonPayload, time.Now()) {
+			req.Header.Set(key, value)
+		}
+	}
 	req.Header.Set("Content-Type", "application/json")
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
 
 	// Execute request
-	resp, err := c.client.Do(req)
+	resp, err = c.client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
@@ -8,14 +8,20 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
 func TestNewHttpClient(t *testing.T) {
 	// Test creating a new HTTP client
 	httpClient := &http.Client{}
-	bearerToken := "test-bearer-token"
+	authenticator := NewBearerToken("test-bearer-token")
 
-	client := NewHttpClient(httpClient, bearerToken)
+	client := NewHttpClient(httpClient, authenticator, nil)
 
 	assert.NotNil(t, client)
 
@@ -23,50 +29,49 @@ func TestNewHttpClient(t *testing.T) {
 	clientImpl, ok := client.(*HttpClientImpl)
 	assert.True(t, ok)
 	assert.Equal(t, httpClient, clientImpl.client)
-	assert.Equal(t, bearerToken, clientImpl.bearerToken)
+	assert.Equal(t, authenticator, clientImpl.authenticator)
 }
 
-func TestNewHttpClient_WithEmptyToken(t *testing.T) {
-	// Test creating a client with empty token
+func TestNewHttpClient_WithoutAuthenticator(t *testing.T) {
+	// Test creating a client without authentication
 	httpClient := &http.Client{}
-	bearerToken := ""
 
-	client := NewHttpClient(httpClient, bearerToken)
+	client := NewHttpClient(httpClient, nil, nil)
 
 	assert.NotNil(t, client)
 
 	clientImpl, ok := client.(*HttpClientImpl)
 	assert.True(t, ok)
 	assert.Equal(t, httpClient, clientImpl.client)
-	assert.Empty(t, clientImpl.bearerToken)
+	assert.Nil(t, clientImpl.authenticator)
 }
 
 func TestNewHttpClient_WithNilHttpClient(t *testing.T) {
 	// Test creating a client with nil http.Client
-	bearerToken := "test-token"
+	authenticator := NewBearerToken("test-token")
 
-	client := NewHttpClient(nil, bearerToken)
+	client := NewHttpClient(nil, authenticator, nil)
 
 	assert.NotNil(t, client)
 
 	clientImpl, ok := client.(*HttpClientImpl)
 	assert.True(t, ok)
 	assert.Nil(t, clientImpl.client)
-	assert.Equal(t, bearerToken, clientImpl.bearerToken)
+	assert.Equal(t, authenticator, clientImpl.authenticator)
 }
 
 func TestHttpClientImpl_Structure(t *testing.T) {
 	// Test the structure of HttpClientImpl
 	httpClient := &http.Client{}
-	bearerToken := "test-structure-token"
+	authenticator := NewBearerToken("test-structure-token")
 
-	client := NewHttpClient(httpClient, bearerToken)
+	client := NewHttpClient(httpClient, authenticator, nil)
 	clientImpl := client.(*HttpClientImpl)
 
 	// Verify fields are accessible and correct
 	assert.Equal(t, httpClient, clientImpl.client)
-	assert.Equal(t, bearerToken, clientImpl.bearerToken)
-	assert.NotEmpty(t, clientImpl.bearerToken)
+	assert.Equal(t, authenticator, clientImpl.authenticator)
+	assert.NotNil(t, clientImpl.authenticator)
 }
 
 func TestHttpClientImpl_Post(t *testing.T) {
@@ -85,7 +90,7 @@ func TestHttpClientImpl_Post(t *testing.T) {
 		}))
 		defer server.Close()
 
-		client := NewHttpClient(server.Client(), "test-token")
+		client := NewHttpClient(server.Client(), NewBearerToken("test-token"), nil)
 		payload := map[string]string{"key": "value"}
 		headers := map[string]string{"Content-Type": "application/json"}
 
@@ -99,13 +104,60 @@ func TestHttpClientImpl_Post(t *testing.T) {
 		assert.JSONEq(t, `{"status":"ok"}`, string(respBody))
 	})
 
+	t.Run("without authenticator", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, ok := r.Header["Authorization"]
+			assert.False(t, ok)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client := NewHttpClient(server.Client(), nil, nil)
+
+		resp, err := client.Post(context.Background(), nil, []byte(`{}`), server.URL)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("signed request", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := ioutil.ReadAll(r.Body)
+			assert.NoError(t, err)
+			timestamp := r.Header.Get(TimestampHeader)
+			assert.NotEmpty(t, timestamp)
+			assert.Equal(t, "key-1", r.Header.Get(KeyIDHeader))
+			assert.Equal(t, "sha256="+Sign([]byte("secret"), timestamp, body), r.Header.Get(SignatureHeader))
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		signer, err := NewSigner("key-1=secret")
+		assert.NoError(t, err)
+		client := NewHttpClient(server.Client(), nil, signer)
+
+		resp, err := client.Post(context.Background(), nil, []byte(`{"key":"value"}`), server.URL)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("authenticator error", func(t *testing.T) {
+		client := NewHttpClient(&http.Client{}, NewBearerTokenFile("/nonexistent/token"), nil)
+
+		_, err := client.Post(context.Background(), nil, []byte(`{}`), "http://localhost")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to authenticate request")
+	})
+
 	t.Run("server error", func(t *testing.T) {
 		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusInternalServerError)
 		}))
 		defer server.Close()
 
-		client := NewHttpClient(server.Client(), "test-token")
+		client := NewHttpClient(server.Client(), NewBearerToken("test-token"), nil)
 		payload := map[string]string{"key": "value"}
 		headers := map[string]string{"Content-Type": "application/json"}
 
@@ -116,7 +168,7 @@ func TestHttpClientImpl_Post(t *testing.T) {
 	})
 
 	t.Run("invalid url", func(t *testing.T) {
-		client := NewHttpClient(&http.Client{}, "test-token")
+		client := NewHttpClient(&http.Client{}, NewBearerToken("test-token"), nil)
 		payload := map[string]string{"key": "value"}
 		headers := map[string]string{"Content-Type": "application/json"}
 
@@ -126,7 +178,7 @@ func TestHttpClientImpl_Post(t *testing.T) {
 	})
 
 	t.Run("payload marshal error", func(t *testing.T) {
-		client := NewHttpClient(&http.Client{}, "test-token")
+		client := NewHttpClient(&http.Client{}, NewBearerToken("test-token"), nil)
 		payload := make(chan int) // Invalid payload for JSON marshaling
 
 		headers := map[string]string{"Content-Type": "application/json"}
@@ -137,3 +189,38 @@ func TestHttpClientImpl_Post(t *testing.T) {
 		assert.Contains(t, err.Error(), "failed to marshal payload")
 	})
 }
+
+func TestHttpClientImpl_Post_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	}()
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx := propagation.TraceContext{}.Extract(context.Background(),
+		propagation.MapCarrier{"traceparent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"})
+	resp, err := NewHttpClient(server.Client(), nil, nil).Post(ctx, nil, []byte(`{}`), server.URL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "POST", span.Name())
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", span.SpanContext().TraceID().String())
+	assert.Equal(t, "b7ad6b7169203331", span.Parent().SpanID().String())
+	assert.Equal(t, codes.Error, span.Status().Code)
+	assert.Contains(t, span.Attributes(), semconv.HTTPResponseStatusCode(http.StatusServiceUnavailable))
+	// the API receives the context of the request span
+	assert.Equal(t, "00-0af7651916cd43dd8448eb211c80319c-"+span.SpanContext().SpanID().String()+"-01", traceparent)
+}
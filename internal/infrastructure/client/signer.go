@@ -0,0 +1,80 @@
+package client
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers set on the requests signed by a Signer.
+const (
+	SignatureHeader = "X-Anyker-Signature"
+	TimestampHeader = "X-Anyker-Timestamp"
+	KeyIDHeader     = "X-Anyker-Key-Id"
+)
+
+// signingKey is a shared secret identified by an id, so receivers know which secret to verify with.
+type signingKey struct {
+	id     string
+	secret []byte
+}
+
+// Signer signs request bodies with HMAC-SHA256, so receivers can verify they come from anyker.
+// Every key signs the timestamp and the body as <timestamp>.<body>, and the signatures are sent in the same order
+// as the key ids, which lets the keys be rotated: the receivers verify with any of the keys they know.
+type Signer struct {
+	keys []signingKey
+}
+
+// NewSigner creates a new Signer from a comma-separated list of id=secret keys.
+// It returns nil if the list is empty, so requests are not signed.
+func NewSigner(keys string) (*Signer, error) {
+	var signer Signer
+	for _, pair := range strings.Split(keys, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		id, secret, ok := strings.Cut(pair, "=")
+		id = strings.TrimSpace(id)
+		if !ok || id == "" || secret == "" {
+			return nil, fmt.Errorf("invalid signing key %q, expected id=secret", id)
+		}
+		if strings.ContainsAny(id, ", ") {
+			return nil, fmt.Errorf("invalid signing key id %q", id)
+		}
+		signer.keys = append(signer.keys, signingKey{id: id, secret: []byte(secret)})
+	}
+	if len(signer.keys) == 0 {
+		return nil, nil
+	}
+	return &signer, nil
+}
+
+// Headers returns the signature headers of a request body sent at the given time.
+func (s *Signer) Headers(body []byte, timestamp time.Time) map[string]string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	ids := make([]string, len(s.keys))
+	signatures := make([]string, len(s.keys))
+	for i, key := range s.keys {
+		ids[i] = key.id
+		signatures[i] = "sha256=" + Sign(key.secret, unix, body)
+	}
+	return map[string]string{
+		SignatureHeader: strings.Join(signatures, ","),
+		TimestampHeader: unix,
+		KeyIDHeader:     strings.Join(ids, ","),
+	}
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of <timestamp>.<body> with the secret.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSigner(t *testing.T) {
+	t.Run("no keys", func(t *testing.T) {
+		signer, err := NewSigner(" ")
+		assert.NoError(t, err)
+		assert.Nil(t, signer)
+	})
+
+	t.Run("keys in order", func(t *testing.T) {
+		signer, err := NewSigner("2025-02=new-secret, 2025-01=old=secret")
+		assert.NoError(t, err)
+		assert.Equal(t, []signingKey{
+			{id: "2025-02", secret: []byte("new-secret")},
+			{id: "2025-01", secret: []byte("old=secret")},
+		}, signer.keys)
+	})
+
+	t.Run("invalid keys", func(t *testing.T) {
+		for _, keys := range []string{"secret", "=secret", "id=", "my id=secret"} {
+			_, err := NewSigner(keys)
+			assert.Error(t, err, keys)
+		}
+	})
+}
+
+func TestSigner_Headers(t *testing.T) {
+	signer, err := NewSigner("new=new-secret,old=old-secret")
+	assert.NoError(t, err)
+	body := []byte(`{"text":"hello"}`)
+	timestamp := time.Unix(1735732800, 0)
+
+	headers := signer.Headers(body, timestamp)
+
+	expected := func(secret string) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(`1735732800.{"text":"hello"}`))
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	assert.Equal(t, "1735732800", headers[TimestampHeader])
+	assert.Equal(t, "new,old", headers[KeyIDHeader])
+	assert.Equal(t, expected("new-secret")+","+expected("old-secret"), headers[SignatureHeader])
+}
@@ -0,0 +1,134 @@
+package client
+
+import (
+	"anyker/config"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// tlsVersions maps the supported minimum TLS versions to their identifiers.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// NewTransport creates the HTTP transport used to forward messages, a copy of the default transport
+// with the TLS configuration: client certificate, CA bundle, minimum TLS version and server name.
+func NewTransport(config config.Config) (*http.Transport, error) {
+	tlsConfig, err := newTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+// newTLSConfig creates the TLS configuration of the forward HTTP client.
+// The client certificate is reloaded when its files change, so rotated certificates are used without restarting.
+func newTLSConfig(config config.Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName: config.HTTPTLSServerName,
+	}
+
+	if config.HTTPTLSMinVersion != "" {
+		version, ok := tlsVersions[config.HTTPTLSMinVersion]
+		if !ok {
+			return nil, fmt.Errorf("unsupported minimum TLS version: %s", config.HTTPTLSMinVersion)
+		}
+		tlsConfig.MinVersion = version
+	}
+
+	if config.HTTPTLSCAFile != "" {
+		ca, err := os.ReadFile(config.HTTPTLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificate found in CA file %s", config.HTTPTLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if (config.HTTPTLSCertFile == "") != (config.HTTPTLSKeyFile == "") {
+		return nil, errors.New("both the client certificate and key files are required")
+	}
+	if config.HTTPTLSCertFile != "" {
+		reloader, err := newCertificateReloader(config.HTTPTLSCertFile, config.HTTPTLSKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.GetClientCertificate = reloader.GetClientCertificate
+	}
+	return tlsConfig, nil
+}
+
+// certificateReloader loads a certificate and its key, and loads them again when the files are modified.
+type certificateReloader struct {
+	certFile string
+	keyFile  string
+
+	mu          sync.Mutex
+	certificate *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+}
+
+// newCertificateReloader creates a new certificateReloader, loading the certificate to fail fast if it is invalid.
+func newCertificateReloader(certFile, keyFile string) (*certificateReloader, error) {
+	reloader := &certificateReloader{certFile: certFile, keyFile: keyFile}
+	if err := reloader.reload(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// GetClientCertificate returns the current client certificate, reloading it first if its files changed.
+// If the new files can't be loaded, e.g. the key has not been written yet, the previous certificate is used.
+func (r *certificateReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	if err := r.reload(); err != nil {
+		log.Warn().Err(err).Msg("failed to reload client certificate, using the previous one")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.certificate, nil
+}
+
+// reload loads the certificate if it has not been loaded yet or its files were modified since the last load.
+func (r *certificateReloader) reload() error {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return fmt.Errorf("failed to read client certificate: %w", err)
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to read client key: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.certificate != nil && certInfo.ModTime().Equal(r.certModTime) && keyInfo.ModTime().Equal(r.keyModTime) {
+		return nil
+	}
+	certificate, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load client certificate: %w", err)
+	}
+	if r.certificate != nil {
+		log.Info().Msgf("client certificate %s reloaded", r.certFile)
+	}
+	r.certificate = &certificate
+	r.certModTime = certInfo.ModTime()
+	r.keyModTime = keyInfo.ModTime()
+	return nil
+}
@@ -0,0 +1,195 @@
+package client
+
+import (
+	"anyker/config"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCA is a certificate authority issuing the certificates of the tests.
+type testCA struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	pem         []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "anyker test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{
+		certificate: certificate,
+		key:         key,
+		pem:         pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// issue returns a PEM certificate and key for the common name, valid for localhost.
+func (ca *testCA) issue(t *testing.T, commonName string, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{"localhost", commonName},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.certificate, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, path string, content []byte, modTime time.Time) {
+	require.NoError(t, os.WriteFile(path, content, 0o600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestNewTransport_MutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	serverCert, serverKey := ca.issue(t, "api.internal", x509.ExtKeyUsageServerAuth)
+	serverCertificate, err := tls.X509KeyPair(serverCert, serverKey)
+	require.NoError(t, err)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.certificate)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCertificate},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+		MinVersion:   tls.VersionTLS12,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	dir := t.TempDir()
+	cfg := config.Config{
+		HTTPTLSCertFile:   filepath.Join(dir, "client.crt"),
+		HTTPTLSKeyFile:    filepath.Join(dir, "client.key"),
+		HTTPTLSCAFile:     filepath.Join(dir, "ca.crt"),
+		HTTPTLSMinVersion: "1.3",
+		HTTPTLSServerName: "api.internal",
+	}
+	clientCert, clientKey := ca.issue(t, "anyker-1", x509.ExtKeyUsageClientAuth)
+	writeFile(t, cfg.HTTPTLSCAFile, ca.pem, time.Now())
+	writeFile(t, cfg.HTTPTLSCertFile, clientCert, time.Now())
+	writeFile(t, cfg.HTTPTLSKeyFile, clientKey, time.Now())
+
+	transport, err := NewTransport(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), transport.TLSClientConfig.MinVersion)
+	client := NewHttpClient(&http.Client{Transport: transport}, nil, nil)
+
+	post := func() string {
+		resp, err := client.Post(context.Background(), nil, []byte(`{}`), server.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body := make([]byte, 64)
+		n, _ := resp.Body.Read(body)
+		return string(body[:n])
+	}
+	assert.Equal(t, "anyker-1", post())
+
+	// rotated certificate, used by the next connections
+	clientCert, clientKey = ca.issue(t, "anyker-2", x509.ExtKeyUsageClientAuth)
+	writeFile(t, cfg.HTTPTLSCertFile, clientCert, time.Now().Add(time.Minute))
+	writeFile(t, cfg.HTTPTLSKeyFile, clientKey, time.Now().Add(time.Minute))
+	transport.CloseIdleConnections()
+
+	assert.Equal(t, "anyker-2", post())
+}
+
+func TestNewTransport_Errors(t *testing.T) {
+	dir := t.TempDir()
+	invalidCA := filepath.Join(dir, "invalid.crt")
+	writeFile(t, invalidCA, []byte("not a certificate"), time.Now())
+
+	tests := []struct {
+		name        string
+		config      config.Config
+		expectedErr string
+	}{
+		{name: "unsupported version", config: config.Config{HTTPTLSMinVersion: "2.0"}, expectedErr: "unsupported minimum TLS version: 2.0"},
+		{name: "missing CA file", config: config.Config{HTTPTLSCAFile: filepath.Join(dir, "missing.crt")}, expectedErr: "failed to read CA file"},
+		{name: "invalid CA file", config: config.Config{HTTPTLSCAFile: invalidCA}, expectedErr: "no certificate found in CA file"},
+		{name: "certificate without key", config: config.Config{HTTPTLSCertFile: invalidCA}, expectedErr: "both the client certificate and key files are required"},
+		{name: "invalid certificate", config: config.Config{HTTPTLSCertFile: invalidCA, HTTPTLSKeyFile: invalidCA}, expectedErr: "failed to load client certificate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewTransport(tt.config)
+			assert.ErrorContains(t, err, tt.expectedErr)
+		})
+	}
+}
+
+func TestNewTransport_Default(t *testing.T) {
+	transport, err := NewTransport(config.Config{})
+
+	assert.NoError(t, err)
+	assert.Nil(t, transport.TLSClientConfig.GetClientCertificate)
+	assert.Nil(t, transport.TLSClientConfig.RootCAs)
+}
+
+func TestCertificateReloader_KeepsPreviousCertificate(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	clientCert, clientKey := ca.issue(t, "anyker-1", x509.ExtKeyUsageClientAuth)
+	writeFile(t, certFile, clientCert, time.Now())
+	writeFile(t, keyFile, clientKey, time.Now())
+
+	reloader, err := newCertificateReloader(certFile, keyFile)
+	require.NoError(t, err)
+	previous, err := reloader.GetClientCertificate(nil)
+	require.NoError(t, err)
+
+	// certificate rotated but key not written yet
+	clientCert, _ = ca.issue(t, "anyker-2", x509.ExtKeyUsageClientAuth)
+	writeFile(t, certFile, clientCert, time.Now().Add(time.Minute))
+
+	current, err := reloader.GetClientCertificate(nil)
+	assert.NoError(t, err)
+	assert.Same(t, previous, current)
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"anyker/config"
+	"anyker/internal/domain"
+	"anyker/internal/metrics"
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/rs/zerolog/log"
+)
+
+// AMQPChannel is the subset of *amqp.Channel used by the AMQP consumer.
+type AMQPChannel interface {
+	Qos(prefetchCount, prefetchSize int, global bool) error
+	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error)
+	QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error
+	Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error)
+	Ack(tag uint64, multiple bool) error
+	Nack(tag uint64, multiple, requeue bool) error
+	Close() error
+}
+
+// AMQPConnection is the subset of *amqp.Connection used by the AMQP consumer.
+type AMQPConnection interface {
+	IsClosed() bool
+	Close() error
+}
+
+// AMQPConsumer implements domain.ConsumerRepository for AMQP 0-9-1 brokers such as RabbitMQ.
+// Messages are consumed from a queue with manual acknowledgements: they are acked once forwarded,
+// and nacked when they could not be.
+type AMQPConsumer struct {
+	connection  AMQPConnection
+	channel     AMQPChannel
+	queue       string
+	exchange    string
+	bindingKeys []string
+	prefetch    int
+	requeue     bool
+	consumerTag string
+	consuming   atomic.Bool
+}
+
+// NewAMQPConsumer connects to the AMQP broker and creates a consumer of the configured queue.
+// The queue is declared and bound when the consumption starts.
+func NewAMQPConsumer(config config.Config) (domain.ConsumerRepository, error) {
+	connection, err := amqp.Dial(config.AMQPURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to AMQP broker: %w", err)
+	}
+	channel, err := connection.Channel()
+	if err != nil {
+		connection.Close()
+		return nil, fmt.Errorf("failed to open AMQP channel: %w", err)
+	}
+	return newAMQPConsumer(config, connection, channel), nil
+}
+
+func newAMQPConsumer(config config.Config, connection AMQPConnection, channel AMQPChannel) *AMQPConsumer {
+	return &AMQPConsumer{
+		connection:  connection,
+		channel:     channel,
+		queue:       config.AMQPQueue,
+		exchange:    config.AMQPExchange,
+		bindingKeys: config.AMQPBindingKeys,
+		prefetch:    config.AMQPPrefetch,
+		requeue:     config.AMQPRequeue,
+		consumerTag: config.NanobotName,
+	}
+}
+
+// Consume declares and binds the queue, then sends its messages to the provided channel
+// until the context is cancelled or the broker closes the channel.
+// Messages not acked yet when the consumer is closed are delivered again by the broker.
+func (c *AMQPConsumer) Consume(ctx context.Context, messages chan<- *domain.Message) error {
+	defer close(messages)
+
+	deliveries, err := c.subscribe()
+	if err != nil {
+		return err
+	}
+	c.consuming.Store(true)
+	defer c.consuming.Store(false)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case delivery, ok := <-deliveries:
+			if !ok {
+				return errors.New("AMQP channel closed by the broker")
+			}
+			message := amqpMessage(c.queue, delivery)
+			log.Debug().Msgf("headers received from AMQP message %v", message.Headers)
+			log.Debug().Msgf("routing key receive from AMQP message %v", message.Key)
+			log.Debug().Msgf("payload receive from AMQP message %v", string(message.Content))
+
+			metrics.MessagesConsumed.WithLabelValues(message.Topic).Inc()
+			messages <- message
+		}
+	}
+}
+
+// subscribe declares the queue, binds it to the exchange if any, and starts consuming it.
+func (c *AMQPConsumer) subscribe() (<-chan amqp.Delivery, error) {
+	if err := c.channel.Qos(c.prefetch, 0, false); err != nil {
+		return nil, fmt.Errorf("failed to set AMQP prefetch: %w", err)
+	}
+	if _, err := c.channel.QueueDeclare(c.queue, true, false, false, false, nil); err != nil {
+		return nil, fmt.Errorf("failed to declare queue %s: %w", c.queue, err)
+	}
+	if c.exchange != "" {
+		keys := c.bindingKeys
+		if len(keys) == 0 {
+			// fanout and headers exchanges ignore the binding key
+			keys = []string{""}
+		}
+		for _, key := range keys {
+			if err := c.channel.QueueBind(c.queue, key, c.exchange, false, nil); err != nil {
+				return nil, fmt.Errorf("failed to bind queue %s to exchange %s with key %s: %w", c.queue, c.exchange, key, err)
+			}
+		}
+	}
+	deliveries, err := c.channel.Consume(c.queue, c.consumerTag, false, false, false, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume queue %s: %w", c.queue, err)
+	}
+	return deliveries, nil
+}
+
+// amqpMessage converts an AMQP delivery to a message. The routing key is the message key,
+// so messages are published with origin:routingId routing keys, and the delivery tag is the offset,
+// to ack the message once processed.
+func amqpMessage(queue string, delivery amqp.Delivery) *domain.Message {
+	headers := make(map[string]string, len(delivery.Headers)+1)
+	for key, value := range delivery.Headers {
+		switch v := value.(type) {
+		case string:
+			headers[key] = v
+		case []byte:
+			headers[key] = string(v)
+		default:
+			headers[key] = fmt.Sprint(v)
+		}
+	}
+	if delivery.CorrelationId != "" && headers[domain.CorrelationIDHeader] == "" {
+		headers[domain.CorrelationIDHeader] = delivery.CorrelationId
+	}
+
+	return &domain.Message{
+		Content:   delivery.Body,
+		Headers:   headers,
+		Key:       delivery.RoutingKey,
+		Topic:     queue,
+		Offset:    int64(delivery.DeliveryTag),
+		Timestamp: delivery.Timestamp,
+	}
+}
+
+// Commit acks a message, so the broker removes it from the queue.
+func (c *AMQPConsumer) Commit(_ context.Context, message domain.Message) error {
+	if err := c.channel.Ack(uint64(message.Offset), false); err != nil {
+		return fmt.Errorf("failed to ack message: %w", err)
+	}
+	return nil
+}
+
+// Reject nacks a message that could not be forwarded. It is requeued if configured so,
+// otherwise the broker discards it or sends it to the dead letter exchange of the queue.
+func (c *AMQPConsumer) Reject(_ context.Context, message domain.Message) error {
+	if err := c.channel.Nack(uint64(message.Offset), false, c.requeue); err != nil {
+		return fmt.Errorf("failed to nack message: %w", err)
+	}
+	return nil
+}
+
+// Live fails when the connection to the broker is closed.
+func (c *AMQPConsumer) Live() error {
+	if c.connection.IsClosed() {
+		return errors.New("AMQP connection is closed")
+	}
+	return nil
+}
+
+// Ready fails until the queue is being consumed.
+func (c *AMQPConsumer) Ready() error {
+	if !c.consuming.Load() {
+		return errors.New("AMQP consumer is not consuming")
+	}
+	return nil
+}
+
+// Close closes the channel, so the messages not acked yet are requeued, and the connection.
+func (c *AMQPConsumer) Close() error {
+	return errors.Join(c.channel.Close(), c.connection.Close())
+}
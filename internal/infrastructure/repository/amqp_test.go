@@ -0,0 +1,272 @@
+package repository
+
+import (
+	"anyker/config"
+	"anyker/internal/domain"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAMQPBroker is an in-process broker with a single queue, implementing AMQPConnection and AMQPChannel.
+// Nacked messages are delivered again when requeued, like a real broker does.
+type fakeAMQPBroker struct {
+	mu         sync.Mutex
+	prefetch   int
+	declared   string
+	bindings   []string
+	consumer   string
+	deliveries chan amqp.Delivery
+	nextTag    uint64
+	unacked    map[uint64]amqp.Delivery
+	acked      []uint64
+	rejected   []uint64
+	closed     bool
+	consumeErr error
+}
+
+func newFakeAMQPBroker() *fakeAMQPBroker {
+	return &fakeAMQPBroker{deliveries: make(chan amqp.Delivery, 10), unacked: make(map[uint64]amqp.Delivery)}
+}
+
+// publish delivers a message to the consumer of the queue.
+func (b *fakeAMQPBroker) publish(delivery amqp.Delivery) {
+	b.mu.Lock()
+	b.nextTag++
+	delivery.DeliveryTag = b.nextTag
+	b.unacked[delivery.DeliveryTag] = delivery
+	b.mu.Unlock()
+	b.deliveries <- delivery
+}
+
+func (b *fakeAMQPBroker) Qos(prefetchCount, _ int, _ bool) error {
+	b.prefetch = prefetchCount
+	return nil
+}
+
+func (b *fakeAMQPBroker) QueueDeclare(name string, _, _, _, _ bool, _ amqp.Table) (amqp.Queue, error) {
+	b.declared = name
+	return amqp.Queue{Name: name}, nil
+}
+
+func (b *fakeAMQPBroker) QueueBind(name, key, exchange string, _ bool, _ amqp.Table) error {
+	b.bindings = append(b.bindings, exchange+"/"+key+"->"+name)
+	return nil
+}
+
+func (b *fakeAMQPBroker) Consume(_, consumer string, _, _, _, _ bool, _ amqp.Table) (<-chan amqp.Delivery, error) {
+	if b.consumeErr != nil {
+		return nil, b.consumeErr
+	}
+	b.consumer = consumer
+	return b.deliveries, nil
+}
+
+func (b *fakeAMQPBroker) Ack(tag uint64, _ bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.unacked[tag]; !ok {
+		return errors.New("unknown delivery tag")
+	}
+	delete(b.unacked, tag)
+	b.acked = append(b.acked, tag)
+	return nil
+}
+
+func (b *fakeAMQPBroker) Nack(tag uint64, _ bool, requeue bool) error {
+	b.mu.Lock()
+	delivery, ok := b.unacked[tag]
+	if !ok {
+		b.mu.Unlock()
+		return errors.New("unknown delivery tag")
+	}
+	delete(b.unacked, tag)
+	b.rejected = append(b.rejected, tag)
+	b.mu.Unlock()
+
+	if requeue {
+		delivery.Redelivered = true
+		b.publish(delivery)
+	}
+	return nil
+}
+
+func (b *fakeAMQPBroker) IsClosed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.closed
+}
+
+func (b *fakeAMQPBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	return nil
+}
+
+func newTestAMQPConsumer(broker *fakeAMQPBroker, requeue bool) *AMQPConsumer {
+	return newAMQPConsumer(config.Config{
+		AMQPQueue:       "messages",
+		AMQPExchange:    "events",
+		AMQPBindingKeys: []string{"telegram.*", "whatsapp.*"},
+		AMQPPrefetch:    5,
+		AMQPRequeue:     requeue,
+		NanobotName:     "anyker-nanobot-1",
+	}, broker, broker)
+}
+
+// receive waits for the next consumed message.
+func receive(t *testing.T, messages <-chan *domain.Message) *domain.Message {
+	t.Helper()
+	select {
+	case message := <-messages:
+		return message
+	case <-time.After(time.Second):
+		t.Fatal("no message consumed")
+		return nil
+	}
+}
+
+func TestAMQPConsumer_Consume(t *testing.T) {
+	broker := newFakeAMQPBroker()
+	consumer := newTestAMQPConsumer(broker, true)
+	ctx, cancel := context.WithCancel(context.Background())
+	messages := make(chan *domain.Message)
+	done := make(chan error)
+	go func() { done <- consumer.Consume(ctx, messages) }()
+
+	timestamp := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	broker.publish(amqp.Delivery{
+		RoutingKey:    "telegram:123",
+		Body:          []byte("hello"),
+		CorrelationId: "corr-1",
+		Timestamp:     timestamp,
+		Headers:       amqp.Table{"source": "bot", "retries": int32(2), "raw": []byte("bytes")},
+	})
+	message := receive(t, messages)
+
+	assert.Equal(t, "telegram:123", message.Key)
+	assert.Equal(t, []byte("hello"), message.Content)
+	assert.Equal(t, "messages", message.Topic)
+	assert.Equal(t, int64(1), message.Offset)
+	assert.Equal(t, timestamp, message.Timestamp)
+	assert.Equal(t, map[string]string{"source": "bot", "retries": "2", "raw": "bytes", domain.CorrelationIDHeader: "corr-1"}, message.Headers)
+
+	assert.Equal(t, 5, broker.prefetch)
+	assert.Equal(t, "messages", broker.declared)
+	assert.Equal(t, []string{"events/telegram.*->messages", "events/whatsapp.*->messages"}, broker.bindings)
+	assert.Equal(t, "anyker-nanobot-1", broker.consumer)
+	assert.NoError(t, consumer.Ready())
+
+	cancel()
+	assert.NoError(t, <-done)
+	_, open := <-messages
+	assert.False(t, open)
+	assert.Error(t, consumer.Ready())
+}
+
+func TestAMQPConsumer_Consume_CorrelationIDHeader(t *testing.T) {
+	// the correlation_id header takes precedence over the correlation id property
+	message := amqpMessage("messages", amqp.Delivery{
+		CorrelationId: "property",
+		Headers:       amqp.Table{domain.CorrelationIDHeader: "header"},
+	})
+
+	assert.Equal(t, "header", message.Headers[domain.CorrelationIDHeader])
+}
+
+func TestAMQPConsumer_Consume_Errors(t *testing.T) {
+	t.Run("consume error", func(t *testing.T) {
+		broker := newFakeAMQPBroker()
+		broker.consumeErr = errors.New("access refused")
+		consumer := newTestAMQPConsumer(broker, true)
+		messages := make(chan *domain.Message)
+
+		err := consumer.Consume(context.Background(), messages)
+
+		assert.ErrorContains(t, err, "failed to consume queue messages")
+		_, open := <-messages
+		assert.False(t, open)
+	})
+
+	t.Run("channel closed by the broker", func(t *testing.T) {
+		broker := newFakeAMQPBroker()
+		consumer := newTestAMQPConsumer(broker, true)
+		close(broker.deliveries)
+
+		err := consumer.Consume(context.Background(), make(chan *domain.Message))
+
+		assert.EqualError(t, err, "AMQP channel closed by the broker")
+	})
+}
+
+func TestAMQPConsumer_NoExchange(t *testing.T) {
+	broker := newFakeAMQPBroker()
+	consumer := newAMQPConsumer(config.Config{AMQPQueue: "messages"}, broker, broker)
+
+	_, err := consumer.subscribe()
+
+	require.NoError(t, err)
+	assert.Empty(t, broker.bindings)
+}
+
+func TestAMQPConsumer_AckNack(t *testing.T) {
+	t.Run("commit acks, reject requeues", func(t *testing.T) {
+		broker := newFakeAMQPBroker()
+		consumer := newTestAMQPConsumer(broker, true)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		messages := make(chan *domain.Message)
+		go consumer.Consume(ctx, messages)
+
+		broker.publish(amqp.Delivery{RoutingKey: "telegram:1"})
+		broker.publish(amqp.Delivery{RoutingKey: "telegram:2"})
+		first := receive(t, messages)
+		second := receive(t, messages)
+
+		require.NoError(t, consumer.Commit(ctx, *first))
+		require.NoError(t, consumer.Reject(ctx, *second))
+		redelivered := receive(t, messages)
+
+		assert.Equal(t, "telegram:2", redelivered.Key)
+		assert.Equal(t, []uint64{1}, broker.acked)
+		assert.Equal(t, []uint64{2}, broker.rejected)
+		require.NoError(t, consumer.Commit(ctx, *redelivered))
+		assert.Equal(t, []uint64{1, 3}, broker.acked)
+	})
+
+	t.Run("reject without requeue", func(t *testing.T) {
+		broker := newFakeAMQPBroker()
+		consumer := newTestAMQPConsumer(broker, false)
+		broker.publish(amqp.Delivery{RoutingKey: "telegram:1"})
+		message := amqpMessage("messages", <-broker.deliveries)
+
+		require.NoError(t, consumer.Reject(context.Background(), *message))
+
+		assert.Equal(t, []uint64{1}, broker.rejected)
+		assert.Empty(t, broker.deliveries)
+	})
+
+	t.Run("unknown delivery tag", func(t *testing.T) {
+		consumer := newTestAMQPConsumer(newFakeAMQPBroker(), true)
+
+		assert.ErrorContains(t, consumer.Commit(context.Background(), domain.Message{Offset: 42}), "failed to ack message")
+		assert.ErrorContains(t, consumer.Reject(context.Background(), domain.Message{Offset: 42}), "failed to nack message")
+	})
+}
+
+func TestAMQPConsumer_Live(t *testing.T) {
+	broker := newFakeAMQPBroker()
+	consumer := newTestAMQPConsumer(broker, true)
+	assert.NoError(t, consumer.Live())
+
+	require.NoError(t, consumer.Close())
+
+	assert.EqualError(t, consumer.Live(), "AMQP connection is closed")
+}
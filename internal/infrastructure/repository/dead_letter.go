@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"anyker/config"
+	"anyker/internal/domain"
+	"context"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"strconv"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// Headers added to dead letters, on top of the original message headers.
+const (
+	deadLetterErrorHeader       = "dlq_error"
+	deadLetterStatusCodeHeader  = "dlq_status_code"
+	deadLetterAttemptsHeader    = "dlq_attempts"
+	deadLetterNanobotNameHeader = "dlq_nanobot_name"
+	deadLetterTimestampHeader   = "dlq_timestamp"
+)
+
+// flushTimeoutMs is how long Close waits for pending messages to be delivered.
+const flushTimeoutMs = 5000
+
+// DeadLetterProducer is a Kafka producer that implements the DeadLetterRepository interface.
+type DeadLetterProducer struct {
+	producer KafkaProducer
+	topic    string
+	// echoCorrelationID adds the generated correlation IDs to the headers of the dead letters
+	echoCorrelationID bool
+}
+
+// NewDeadLetterProducer creates a new Kafka producer for the configured dead-letter topic.
+func NewDeadLetterProducer(config config.Config) (domain.DeadLetterRepository, error) {
+	configMap, err := newKafkaConfigMap(config, nil)
+	if err != nil {
+		return nil, err
+	}
+	p, err := kafka.NewProducer(configMap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dead-letter producer: %w", err)
+	}
+	go logProducerEvents("dead-letter", p.Events())
+
+	return &DeadLetterProducer{
+		producer:          p,
+		topic:             config.DeadLetterTopic,
+		echoCorrelationID: config.CorrelationIDEcho,
+	}, nil
+}
+
+// Publish produces the original message content, key and headers to the dead-letter topic,
+// with the failure metadata added as headers.
+func (p *DeadLetterProducer) Publish(ctx context.Context, deadLetter domain.DeadLetter) error {
+	headers := make([]kafka.Header, 0, len(deadLetter.Message.Headers)+6)
+	correlationID := correlationIDHeader(deadLetter.Message, p.echoCorrelationID)
+	for key, value := range deadLetter.Message.Headers {
+		if correlationID != nil && key == domain.CorrelationIDHeader {
+			// empty, replaced by the generated one
+			continue
+		}
+		headers = append(headers, kafka.Header{Key: key, Value: []byte(value)})
+	}
+	if correlationID != nil {
+		headers = append(headers, *correlationID)
+	}
+	headers = append(headers,
+		kafka.Header{Key: deadLetterErrorHeader, Value: []byte(deadLetter.Error)},
+		kafka.Header{Key: deadLetterStatusCodeHeader, Value: []byte(strconv.Itoa(deadLetter.StatusCode))},
+		kafka.Header{Key: deadLetterAttemptsHeader, Value: []byte(strconv.Itoa(deadLetter.Attempts))},
+		kafka.Header{Key: deadLetterNanobotNameHeader, Value: []byte(deadLetter.NanobotName)},
+		kafka.Header{Key: deadLetterTimestampHeader, Value: []byte(deadLetter.Timestamp.UTC().Format(time.RFC3339Nano))},
+	)
+
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &p.topic, Partition: kafka.PartitionAny},
+		Key:            []byte(deadLetter.Message.Key),
+		Value:          deadLetter.Message.Content,
+		Headers:        headers,
+	}
+	if err := produce(ctx, p.producer, msg); err != nil {
+		return err
+	}
+	log.Ctx(ctx).Debug().Msgf("dead letter published to topic %s", p.topic)
+
+	return nil
+}
+
+// correlationIDHeader returns the correlation_id header of a generated correlation ID when echo is enabled,
+// or nil if the message already had the header.
+func correlationIDHeader(message domain.Message, echo bool) *kafka.Header {
+	if !echo || message.CorrelationID == "" || message.Headers[domain.CorrelationIDHeader] != "" {
+		return nil
+	}
+	return &kafka.Header{Key: domain.CorrelationIDHeader, Value: []byte(message.CorrelationID)}
+}
+
+// Close flushes pending dead letters and closes the Kafka producer.
+func (p *DeadLetterProducer) Close() error {
+	if remaining := p.producer.Flush(flushTimeoutMs); remaining > 0 {
+		log.Warn().Msgf("%d dead letters were not delivered before closing", remaining)
+	}
+	p.producer.Close()
+	return nil
+}
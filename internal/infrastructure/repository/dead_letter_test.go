@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"anyker/config"
+	"anyker/internal/domain"
+	"anyker/internal/infrastructure/repository/mocks"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestNewDeadLetterProducer(t *testing.T) {
+	cfg := config.Config{
+		KafkaBroker:     "localhost:9092",
+		DeadLetterTopic: "test-dlq",
+	}
+
+	producer, err := NewDeadLetterProducer(cfg)
+	assert.NoError(t, err)
+	assert.NotNil(t, producer)
+	assert.Equal(t, "test-dlq", producer.(*DeadLetterProducer).topic)
+
+	// close without flushing, there is no broker to deliver to
+	producer.(*DeadLetterProducer).producer.Close()
+}
+
+func TestDeadLetterProducer_Publish(t *testing.T) {
+	timestamp := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	deadLetter := domain.DeadLetter{
+		Message: domain.Message{
+			Content: []byte("message1"),
+			Headers: map[string]string{"correlation_id": "123"},
+			Key:     "service-a:user-123",
+		},
+		Error:       "unexpected status code: 503",
+		StatusCode:  503,
+		Attempts:    3,
+		NanobotName: "test-nanobot",
+		Timestamp:   timestamp,
+	}
+	deliver := func(err error) func(mock.Arguments) {
+		return func(args mock.Arguments) {
+			msg := args.Get(0).(*kafka.Message)
+			msg.TopicPartition.Error = err
+			args.Get(1).(chan kafka.Event) <- msg
+		}
+	}
+
+	t.Run("success", func(t *testing.T) {
+		mockKafkaProducer := new(mocks.KafkaProducer)
+		producer := &DeadLetterProducer{producer: mockKafkaProducer, topic: "test-dlq"}
+		defer mockKafkaProducer.AssertExpectations(t)
+
+		var produced *kafka.Message
+		mockKafkaProducer.On("Produce", mock.AnythingOfType("*kafka.Message"), mock.Anything).
+			Run(func(args mock.Arguments) {
+				produced = args.Get(0).(*kafka.Message)
+				deliver(nil)(args)
+			}).Return(nil).Once()
+
+		err := producer.Publish(context.Background(), deadLetter)
+		assert.NoError(t, err)
+
+		assert.Equal(t, "test-dlq", *produced.TopicPartition.Topic)
+		assert.Equal(t, "service-a:user-123", string(produced.Key))
+		assert.Equal(t, "message1", string(produced.Value))
+
+		headers := make(map[string]string)
+		for _, h := range produced.Headers {
+			headers[h.Key] = string(h.Value)
+		}
+		assert.Equal(t, "123", headers["correlation_id"])
+		assert.Equal(t, "unexpected status code: 503", headers[deadLetterErrorHeader])
+		assert.Equal(t, "503", headers[deadLetterStatusCodeHeader])
+		assert.Equal(t, "3", headers[deadLetterAttemptsHeader])
+		assert.Equal(t, "test-nanobot", headers[deadLetterNanobotNameHeader])
+		assert.Equal(t, "2025-01-01T12:00:00Z", headers[deadLetterTimestampHeader])
+	})
+
+	t.Run("produce error", func(t *testing.T) {
+		mockKafkaProducer := new(mocks.KafkaProducer)
+		producer := &DeadLetterProducer{producer: mockKafkaProducer, topic: "test-dlq"}
+		defer mockKafkaProducer.AssertExpectations(t)
+
+		mockKafkaProducer.On("Produce", mock.Anything, mock.Anything).Return(errors.New("queue full")).Once()
+
+		err := producer.Publish(context.Background(), deadLetter)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "queue full")
+	})
+
+	t.Run("delivery error", func(t *testing.T) {
+		mockKafkaProducer := new(mocks.KafkaProducer)
+		producer := &DeadLetterProducer{producer: mockKafkaProducer, topic: "test-dlq"}
+		defer mockKafkaProducer.AssertExpectations(t)
+
+		mockKafkaProducer.On("Produce", mock.Anything, mock.Anything).
+			Run(deliver(kafka.NewError(kafka.ErrMsgTimedOut, "Local: Message timed out", false))).
+			Return(nil).Once()
+
+		err := producer.Publish(context.Background(), deadLetter)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to deliver message")
+	})
+
+	t.Run("context cancelled while waiting for delivery", func(t *testing.T) {
+		mockKafkaProducer := new(mocks.KafkaProducer)
+		producer := &DeadLetterProducer{producer: mockKafkaProducer, topic: "test-dlq"}
+		defer mockKafkaProducer.AssertExpectations(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		mockKafkaProducer.On("Produce", mock.Anything, mock.Anything).
+			Run(func(mock.Arguments) { cancel() }).Return(nil).Once()
+
+		err := producer.Publish(ctx, deadLetter)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestDeadLetterProducer_Publish_CorrelationID(t *testing.T) {
+	tests := []struct {
+		name     string
+		echo     bool
+		message  domain.Message
+		expected []kafka.Header
+	}{
+		{
+			name:     "generated, echoed",
+			echo:     true,
+			message:  domain.Message{CorrelationID: "generated"},
+			expected: []kafka.Header{{Key: "correlation_id", Value: []byte("generated")}},
+		},
+		{
+			name:     "generated, empty header replaced",
+			echo:     true,
+			message:  domain.Message{CorrelationID: "generated", Headers: map[string]string{"correlation_id": ""}},
+			expected: []kafka.Header{{Key: "correlation_id", Value: []byte("generated")}},
+		},
+		{
+			name:     "generated, not echoed",
+			message:  domain.Message{CorrelationID: "generated"},
+			expected: []kafka.Header{},
+		},
+		{
+			name:     "from header",
+			echo:     true,
+			message:  domain.Message{CorrelationID: "abc", Headers: map[string]string{"correlation_id": "abc"}},
+			expected: []kafka.Header{{Key: "correlation_id", Value: []byte("abc")}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockKafkaProducer := new(mocks.KafkaProducer)
+			producer := &DeadLetterProducer{producer: mockKafkaProducer, topic: "test-dlq", echoCorrelationID: tt.echo}
+
+			var produced *kafka.Message
+			mockKafkaProducer.On("Produce", mock.AnythingOfType("*kafka.Message"), mock.Anything).
+				Run(func(args mock.Arguments) {
+					produced = args.Get(0).(*kafka.Message)
+					args.Get(1).(chan kafka.Event) <- produced
+				}).Return(nil).Once()
+
+			assert.NoError(t, producer.Publish(context.Background(), domain.DeadLetter{Message: tt.message}))
+
+			correlationIDs := []kafka.Header{}
+			for _, h := range produced.Headers {
+				if h.Key == "correlation_id" {
+					correlationIDs = append(correlationIDs, h)
+				}
+			}
+			assert.Equal(t, tt.expected, correlationIDs)
+		})
+	}
+}
+
+func TestDeadLetterProducer_Close(t *testing.T) {
+	mockKafkaProducer := new(mocks.KafkaProducer)
+	producer := &DeadLetterProducer{producer: mockKafkaProducer, topic: "test-dlq"}
+	defer mockKafkaProducer.AssertExpectations(t)
+
+	mockKafkaProducer.On("Flush", flushTimeoutMs).Return(0).Once()
+	mockKafkaProducer.On("Close").Return().Once()
+
+	assert.NoError(t, producer.Close())
+}
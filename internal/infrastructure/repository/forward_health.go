@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// forwardHealth tracks the consecutive failures of a forward repository, reported by its Ready method:
+// a failing destination takes the worker out of service, restarting it would not fix the destination.
+type forwardHealth struct {
+	maxFailures         int
+	consecutiveFailures atomic.Int64
+	// lastSuccess is the time of the last successful forward in Unix nanoseconds, 0 before the first one
+	lastSuccess atomic.Int64
+}
+
+// succeeded records a successful forward, resetting the consecutive failures.
+func (h *forwardHealth) succeeded() {
+	h.consecutiveFailures.Store(0)
+	h.lastSuccess.Store(time.Now().UnixNano())
+}
+
+// failed records a failed forward, the forwards cancelled on shutdown are not counted.
+func (h *forwardHealth) failed(err error) {
+	if !errors.Is(err, context.Canceled) {
+		h.consecutiveFailures.Add(1)
+	}
+}
+
+// ready returns an error when the number of consecutive failures forwarding to the destination
+// reaches the configured maximum.
+func (h *forwardHealth) ready(destination string) error {
+	failures := h.consecutiveFailures.Load()
+	if h.maxFailures <= 0 || failures < int64(h.maxFailures) {
+		return nil
+	}
+	if lastSuccess := h.lastSuccess.Load(); lastSuccess != 0 {
+		return fmt.Errorf("%d consecutive failures forwarding to %s, last success at %s",
+			failures, destination, time.Unix(0, lastSuccess).Format(time.RFC3339))
+	}
+	return fmt.Errorf("%d consecutive failures forwarding to %s", failures, destination)
+}
@@ -0,0 +1,204 @@
+package repository
+
+import (
+	forwardv1 "anyker/api/forward/v1"
+	"anyker/config"
+	"anyker/internal/domain"
+	"anyker/internal/metrics"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// GRPCForwardRepository implements the domain.ForwardRepository interface with the Forward method
+// of the anyker.forward.v1.Forwarder gRPC service.
+type GRPCForwardRepository struct {
+	config      config.Config
+	conn        *grpc.ClientConn
+	client      forwardv1.ForwarderClient
+	retryPolicy retryPolicy
+	// replyRepository publishes the responses of the service, nil to discard them
+	replyRepository domain.ReplyRepository
+
+	// health state reported by Ready
+	health forwardHealth
+}
+
+// NewGRPCForwardRepository creates a new GRPCForwardRepository calling the service over the given connection,
+// which is closed with the repository. The reply repository is optional, when nil the responses of the service are discarded.
+func NewGRPCForwardRepository(
+	config config.Config,
+	conn *grpc.ClientConn,
+	replyRepository domain.ReplyRepository) domain.ForwardRepository {
+	return &GRPCForwardRepository{
+		config:          config,
+		conn:            conn,
+		client:          forwardv1.NewForwarderClient(conn),
+		retryPolicy:     newRetryPolicy(config),
+		replyRepository: replyRepository,
+		health:          forwardHealth{maxFailures: config.HealthMaxForwardFailures},
+	}
+}
+
+// Forward forwards a message to the gRPC service. Each attempt has the configured timeout as deadline,
+// or the deadline of the context if it is earlier, which the service receives with the call.
+// Attempts failing with a transient status code are retried according to the configured retry policy,
+// if all attempts fail a *domain.ForwardError is returned.
+// With a reply repository, the response of the service is published as a reply.
+func (f *GRPCForwardRepository) Forward(ctx context.Context, message domain.Message) error {
+	request := f.request(message)
+	start := time.Now()
+	defer func() {
+		metrics.ForwardDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	for attempt := 1; ; attempt++ {
+		response, err := f.call(ctx, request)
+		if err == nil {
+			f.health.succeeded()
+			if f.replyRepository != nil {
+				f.publishReply(ctx, message, response)
+			}
+			return nil
+		}
+		if attempt >= f.retryPolicy.maxAttempts || !retryableCode(ctx, err) {
+			return f.failed(attempt, err)
+		}
+
+		delay := f.retryPolicy.delay(attempt, 0)
+		log.Ctx(ctx).Warn().Err(err).Int("attempt", attempt).Dur("delay", delay).Msg("failed to forward message, retrying")
+
+		if sleepErr := sleep(ctx, delay); sleepErr != nil {
+			// shutting down, give up with the last error
+			return f.failed(attempt, err)
+		}
+	}
+}
+
+// request creates the request of a message.
+func (f *GRPCForwardRepository) request(message domain.Message) *forwardv1.ForwardRequest {
+	origin, routingID := domain.ParseKey(message.Key)
+	correlationID := message.CorrelationID
+	if correlationID == "" {
+		correlationID = message.Headers[domain.CorrelationIDHeader]
+	}
+	request := &forwardv1.ForwardRequest{
+		Content:       message.Content,
+		Key:           message.Key,
+		Origin:        origin,
+		RoutingId:     routingID,
+		Headers:       message.Headers,
+		CorrelationId: correlationID,
+		Topic:         message.Topic,
+		Partition:     message.Partition,
+		Offset:        message.Offset,
+		Id:            message.ID,
+		NanobotName:   f.config.NanobotName,
+	}
+	if !message.Timestamp.IsZero() {
+		request.Timestamp = timestamppb.New(message.Timestamp)
+	}
+	return request
+}
+
+// call makes a single forward attempt.
+func (f *GRPCForwardRepository) call(ctx context.Context, request *forwardv1.ForwardRequest) (*forwardv1.ForwardResponse, error) {
+	callCtx := ctx
+	if f.config.GRPCTimeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, f.config.GRPCTimeout)
+		defer cancel()
+	}
+	response, err := f.client.Forward(callCtx, request)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			// the forward was cancelled or timed out, not only the call
+			return nil, fmt.Errorf("%w: %w", ctxErr, err)
+		}
+		return nil, err
+	}
+	log.Ctx(ctx).Info().Msg("message forwarded over gRPC")
+	return response, nil
+}
+
+// publishReply publishes the response to a forwarded message, with the 200 status code of a successful HTTP forward.
+// A reply that can't be published is only logged, as failing would forward the message again.
+func (f *GRPCForwardRepository) publishReply(ctx context.Context, message domain.Message, response *forwardv1.ForwardResponse) {
+	reply := domain.Reply{
+		Message:     message,
+		StatusCode:  http.StatusOK,
+		ContentType: response.GetContentType(),
+		Content:     response.GetContent(),
+		NanobotName: f.config.NanobotName,
+		Timestamp:   time.Now(),
+	}
+	if err := f.replyRepository.Publish(ctx, reply); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("failed to publish reply")
+	}
+}
+
+// retryableCode reports whether an attempt that failed with the given error should be retried:
+// the service is unavailable, overloaded or aborted the call, or the attempt timed out while the forward still can go on.
+func retryableCode(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
+
+// Live always returns nil, the failures of the destination are reported by Ready.
+func (f *GRPCForwardRepository) Live() error {
+	return nil
+}
+
+// Ready returns an error when the number of consecutive forward failures reaches the configured maximum,
+// the connection is established on the first forward.
+func (f *GRPCForwardRepository) Ready() error {
+	return f.health.ready(f.config.GRPCTarget)
+}
+
+// Close closes the connection to the service.
+func (f *GRPCForwardRepository) Close() error {
+	return f.conn.Close()
+}
+
+// failed counts a message that could not be forwarded and returns the error describing the failure.
+func (f *GRPCForwardRepository) failed(attempts int, err error) error {
+	errorClass := grpcErrorClass(err)
+	metrics.ForwardFailures.WithLabelValues("0", errorClass).Inc()
+	f.health.failed(err)
+	return &domain.ForwardError{
+		ClientError: errorClass == "client_error",
+		Attempts:    attempts,
+		Err:         fmt.Errorf("gRPC call failed: %w", err),
+	}
+}
+
+// grpcErrorClass classifies a gRPC forward failure for the metrics, with the classes of the HTTP ones.
+func grpcErrorClass(err error) string {
+	switch status.Code(err) {
+	case codes.Canceled:
+		return "canceled"
+	case codes.DeadlineExceeded:
+		return "timeout"
+	case codes.Unavailable:
+		return "transport"
+	case codes.InvalidArgument, codes.NotFound, codes.AlreadyExists, codes.PermissionDenied,
+		codes.FailedPrecondition, codes.OutOfRange, codes.Unauthenticated:
+		return "client_error"
+	default:
+		return "server_error"
+	}
+}
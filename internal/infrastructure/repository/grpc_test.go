@@ -0,0 +1,225 @@
+package repository
+
+import (
+	forwardv1 "anyker/api/forward/v1"
+	"anyker/config"
+	"anyker/internal/domain"
+	domainmocks "anyker/internal/domain/mocks"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// fakeForwarder is a Forwarder service failing with the queued errors, then succeeding.
+type fakeForwarder struct {
+	forwardv1.UnimplementedForwarderServer
+	mu        sync.Mutex
+	errs      []error
+	requests  []*forwardv1.ForwardRequest
+	deadlines []time.Duration
+	response  *forwardv1.ForwardResponse
+}
+
+func (f *fakeForwarder) Forward(ctx context.Context, request *forwardv1.ForwardRequest) (*forwardv1.ForwardResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, request)
+	if deadline, ok := ctx.Deadline(); ok {
+		f.deadlines = append(f.deadlines, time.Until(deadline))
+	}
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return nil, err
+	}
+	if f.response != nil {
+		return f.response, nil
+	}
+	return &forwardv1.ForwardResponse{}, nil
+}
+
+// startForwarder serves the fake forwarder in memory and returns a connection to it.
+func startForwarder(t *testing.T, forwarder *fakeForwarder) *grpc.ClientConn {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	forwardv1.RegisterForwarderServer(server, forwarder)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func grpcConfig() config.Config {
+	return config.Config{
+		GRPCTarget:              "bufconn",
+		GRPCTimeout:             5 * time.Second,
+		NanobotName:             "test-nanobot",
+		ForwardRetryMaxAttempts: 3,
+		ForwardRetryBaseDelay:   time.Millisecond,
+	}
+}
+
+func TestGRPCForwardRepository_Forward(t *testing.T) {
+	forwarder := &fakeForwarder{}
+	repo := NewGRPCForwardRepository(grpcConfig(), startForwarder(t, forwarder), nil)
+	timestamp := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	msg := domain.Message{
+		Content:       []byte(`{"text":"hello"}`),
+		Key:           "telegram:123",
+		Headers:       map[string]string{"type": "text"},
+		CorrelationID: "abc",
+		Topic:         "orders",
+		Partition:     2,
+		Offset:        42,
+		Timestamp:     timestamp,
+	}
+
+	require.NoError(t, repo.Forward(context.Background(), msg))
+
+	require.Len(t, forwarder.requests, 1)
+	assert.True(t, proto.Equal(&forwardv1.ForwardRequest{
+		Content:       []byte(`{"text":"hello"}`),
+		Key:           "telegram:123",
+		Origin:        "telegram",
+		RoutingId:     "123",
+		Headers:       map[string]string{"type": "text"},
+		CorrelationId: "abc",
+		Topic:         "orders",
+		Partition:     2,
+		Offset:        42,
+		Timestamp:     timestamppb.New(timestamp),
+		NanobotName:   "test-nanobot",
+	}, forwarder.requests[0]), "unexpected request %v", forwarder.requests[0])
+	assert.NoError(t, repo.(*GRPCForwardRepository).Ready())
+}
+
+func TestGRPCForwardRepository_Forward_Deadline(t *testing.T) {
+	t.Run("call timeout", func(t *testing.T) {
+		forwarder := &fakeForwarder{}
+		repo := NewGRPCForwardRepository(grpcConfig(), startForwarder(t, forwarder), nil)
+
+		require.NoError(t, repo.Forward(context.Background(), domain.Message{Key: "telegram:123"}))
+
+		require.Len(t, forwarder.deadlines, 1)
+		assert.InDelta(t, 5*time.Second, forwarder.deadlines[0], float64(time.Second))
+	})
+
+	t.Run("earlier deadline of the forward", func(t *testing.T) {
+		forwarder := &fakeForwarder{}
+		repo := NewGRPCForwardRepository(grpcConfig(), startForwarder(t, forwarder), nil)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		require.NoError(t, repo.Forward(ctx, domain.Message{Key: "telegram:123"}))
+
+		require.Len(t, forwarder.deadlines, 1)
+		assert.LessOrEqual(t, forwarder.deadlines[0], time.Second)
+	})
+}
+
+func TestGRPCForwardRepository_Forward_Retry(t *testing.T) {
+	t.Run("transient errors are retried", func(t *testing.T) {
+		forwarder := &fakeForwarder{errs: []error{
+			status.Error(codes.Unavailable, "restarting"),
+			status.Error(codes.ResourceExhausted, "overloaded"),
+		}}
+		repo := NewGRPCForwardRepository(grpcConfig(), startForwarder(t, forwarder), nil)
+
+		require.NoError(t, repo.Forward(context.Background(), domain.Message{Key: "telegram:123"}))
+
+		assert.Len(t, forwarder.requests, 3)
+	})
+
+	t.Run("all attempts fail", func(t *testing.T) {
+		forwarder := &fakeForwarder{errs: []error{
+			status.Error(codes.Unavailable, "down"),
+			status.Error(codes.Unavailable, "down"),
+			status.Error(codes.Unavailable, "down"),
+		}}
+		cfg := grpcConfig()
+		cfg.HealthMaxForwardFailures = 1
+		repo := NewGRPCForwardRepository(cfg, startForwarder(t, forwarder), nil)
+
+		err := repo.Forward(context.Background(), domain.Message{Key: "telegram:123"})
+
+		var forwardErr *domain.ForwardError
+		require.ErrorAs(t, err, &forwardErr)
+		assert.Equal(t, 3, forwardErr.Attempts)
+		assert.False(t, forwardErr.ClientError)
+		assert.Equal(t, codes.Unavailable, status.Code(forwardErr.Err))
+		assert.EqualError(t, repo.(*GRPCForwardRepository).Ready(), "1 consecutive failures forwarding to bufconn")
+		assert.NoError(t, repo.(*GRPCForwardRepository).Live())
+	})
+
+	t.Run("permanent errors are not retried", func(t *testing.T) {
+		forwarder := &fakeForwarder{errs: []error{status.Error(codes.InvalidArgument, "invalid content")}}
+		repo := NewGRPCForwardRepository(grpcConfig(), startForwarder(t, forwarder), nil)
+
+		err := repo.Forward(context.Background(), domain.Message{Key: "telegram:123"})
+
+		var forwardErr *domain.ForwardError
+		require.ErrorAs(t, err, &forwardErr)
+		assert.Equal(t, 1, forwardErr.Attempts)
+		assert.True(t, forwardErr.ClientError)
+		assert.ErrorContains(t, err, "invalid content")
+	})
+
+	t.Run("cancelled forward", func(t *testing.T) {
+		forwarder := &fakeForwarder{}
+		repo := NewGRPCForwardRepository(grpcConfig(), startForwarder(t, forwarder), nil)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := repo.Forward(ctx, domain.Message{Key: "telegram:123"})
+
+		assert.True(t, errors.Is(err, context.Canceled))
+		assert.NoError(t, repo.(*GRPCForwardRepository).Ready())
+	})
+}
+
+func TestGRPCForwardRepository_Forward_Reply(t *testing.T) {
+	forwarder := &fakeForwarder{response: &forwardv1.ForwardResponse{Content: []byte(`{"text":"hi"}`), ContentType: "application/json"}}
+	mockReplyRepo := new(domainmocks.MockReplyRepository)
+	repo := NewGRPCForwardRepository(grpcConfig(), startForwarder(t, forwarder), mockReplyRepo)
+	msg := domain.Message{Key: "telegram:123", CorrelationID: "abc"}
+	mockReplyRepo.On("Publish", mock.Anything, mock.MatchedBy(func(reply domain.Reply) bool {
+		return reply.Message.Key == msg.Key && reply.StatusCode == http.StatusOK &&
+			reply.ContentType == "application/json" && string(reply.Content) == `{"text":"hi"}` &&
+			reply.NanobotName == "test-nanobot"
+	})).Return(nil).Once()
+
+	require.NoError(t, repo.Forward(context.Background(), msg))
+
+	mockReplyRepo.AssertExpectations(t)
+}
+
+func TestGRPCForwardRepository_Close(t *testing.T) {
+	conn := startForwarder(t, &fakeForwarder{})
+	repo := NewGRPCForwardRepository(grpcConfig(), conn, nil)
+
+	require.NoError(t, repo.(*GRPCForwardRepository).Close())
+
+	assert.Equal(t, connectivity.Shutdown, conn.GetState())
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"anyker/config"
+	"anyker/internal/domain"
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Metadata headers set on the forwarded requests when enabled.
+const (
+	KafkaTopicHeader     = "X-Kafka-Topic"
+	KafkaPartitionHeader = "X-Kafka-Partition"
+	KafkaOffsetHeader    = "X-Kafka-Offset"
+	KafkaTimestampHeader = "X-Kafka-Timestamp"
+	MessageIDHeader      = "X-Message-ID"
+	NanobotNameHeader    = "X-Nanobot-Name"
+)
+
+// headerMapping decides which Kafka headers of a message are sent as HTTP headers, and with which names.
+// The headers matching the allowlist of glob patterns are sent with the prefix, the renamed ones are always sent
+// with their new name, without prefix.
+type headerMapping struct {
+	include     []string
+	prefix      string
+	rename      map[string]string
+	metadata    bool
+	nanobotName string
+}
+
+// newHeaderMapping creates a headerMapping from the forward headers configuration.
+// It returns an error if a header pattern is invalid, as ignoring it would silently stop forwarding the headers.
+func newHeaderMapping(config config.Config) (headerMapping, error) {
+	for _, pattern := range config.ForwardHeaders {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return headerMapping{}, fmt.Errorf("invalid header pattern %s: %w", pattern, err)
+		}
+	}
+	return headerMapping{
+		include:     config.ForwardHeaders,
+		prefix:      config.ForwardHeaderPrefix,
+		rename:      config.ForwardHeaderRename,
+		metadata:    config.ForwardMetadataHeaders,
+		nanobotName: config.NanobotName,
+	}, nil
+}
+
+// headers returns the HTTP headers of a forwarded message: the mapped Kafka headers, the metadata headers if enabled,
+// and the correlation and routing ids, which take precedence over the others.
+func (m headerMapping) headers(ctx context.Context, message domain.Message) map[string]string {
+	headers := make(map[string]string)
+	for key, value := range message.Headers {
+		name, ok := m.name(key)
+		if !ok {
+			continue
+		}
+		if !validHeaderName(name) || !validHeaderValue(value) {
+			log.Ctx(ctx).Debug().Msgf("header %s not forwarded, it is not a valid HTTP header", key)
+			continue
+		}
+		headers[name] = value
+	}
+
+	if m.metadata {
+		headers[KafkaTopicHeader] = message.Topic
+		headers[KafkaPartitionHeader] = strconv.Itoa(int(message.Partition))
+		headers[KafkaOffsetHeader] = strconv.FormatInt(message.Offset, 10)
+		if !message.Timestamp.IsZero() {
+			headers[KafkaTimestampHeader] = message.Timestamp.UTC().Format(time.RFC3339Nano)
+		}
+		if message.ID != "" {
+			headers[MessageIDHeader] = message.ID
+		}
+		if m.nanobotName != "" {
+			headers[NanobotNameHeader] = m.nanobotName
+		}
+	}
+
+	headers["X-Correlation-ID"] = message.CorrelationID
+	if message.CorrelationID == "" {
+		headers["X-Correlation-ID"] = message.Headers[domain.CorrelationIDHeader]
+	}
+	headers["X-Routing-ID"] = message.Key
+	return headers
+}
+
+// name returns the HTTP header name of a Kafka header, and whether it is forwarded.
+func (m headerMapping) name(key string) (string, bool) {
+	if name, ok := m.rename[key]; ok {
+		return name, true
+	}
+	for _, pattern := range m.include {
+		if matched, _ := path.Match(pattern, key); matched {
+			return m.prefix + key, true
+		}
+	}
+	return "", false
+}
+
+// validHeaderName reports whether name is a valid HTTP header name, i.e. a non-empty RFC 7230 token.
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range []byte(name) {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// validHeaderValue reports whether value can be sent as an HTTP header value, without control characters.
+func validHeaderValue(value string) bool {
+	for _, c := range []byte(value) {
+		if c < ' ' && c != '\t' || c == 0x7f {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"anyker/config"
+	"anyker/internal/domain"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeaderMapping_Headers(t *testing.T) {
+	message := domain.Message{
+		Key: "telegram:123",
+		Headers: map[string]string{
+			"correlation_id": "abc",
+			"type":           "text",
+			"trace_level":    "debug",
+			"user id":        "not a valid header name",
+			"multiline":      "first\r\nsecond",
+		},
+		Topic:     "orders",
+		Partition: 2,
+		Offset:    42,
+		Timestamp: time.Date(2025, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600)),
+	}
+
+	tests := []struct {
+		name     string
+		config   config.Config
+		expected map[string]string
+	}{
+		{
+			name:   "default",
+			config: config.Config{ForwardHeaderPrefix: "X-Kafka-Header-"},
+			expected: map[string]string{
+				"X-Correlation-ID": "abc",
+				"X-Routing-ID":     "telegram:123",
+			},
+		},
+		{
+			name:   "all headers",
+			config: config.Config{ForwardHeaders: []string{"*"}, ForwardHeaderPrefix: "X-Kafka-Header-"},
+			expected: map[string]string{
+				"X-Kafka-Header-correlation_id": "abc",
+				"X-Kafka-Header-type":           "text",
+				"X-Kafka-Header-trace_level":    "debug",
+				"X-Correlation-ID":              "abc",
+				"X-Routing-ID":                  "telegram:123",
+			},
+		},
+		{
+			name:   "allowlist",
+			config: config.Config{ForwardHeaders: []string{"type", "trace_*"}, ForwardHeaderPrefix: "X-Meta-"},
+			expected: map[string]string{
+				"X-Meta-type":        "text",
+				"X-Meta-trace_level": "debug",
+				"X-Correlation-ID":   "abc",
+				"X-Routing-ID":       "telegram:123",
+			},
+		},
+		{
+			name: "rename",
+			config: config.Config{
+				ForwardHeaders:      []string{"*"},
+				ForwardHeaderPrefix: "X-Kafka-Header-",
+				ForwardHeaderRename: map[string]string{"type": "X-Message-Type", "user id": "X-User-ID"},
+			},
+			expected: map[string]string{
+				"X-Kafka-Header-correlation_id": "abc",
+				"X-Message-Type":                "text",
+				"X-User-ID":                     "not a valid header name",
+				"X-Kafka-Header-trace_level":    "debug",
+				"X-Correlation-ID":              "abc",
+				"X-Routing-ID":                  "telegram:123",
+			},
+		},
+		{
+			name:   "metadata",
+			config: config.Config{ForwardMetadataHeaders: true, NanobotName: "nanobot-1"},
+			expected: map[string]string{
+				"X-Kafka-Topic":     "orders",
+				"X-Kafka-Partition": "2",
+				"X-Kafka-Offset":    "42",
+				"X-Kafka-Timestamp": "2025-01-02T02:04:05Z",
+				"X-Nanobot-Name":    "nanobot-1",
+				"X-Correlation-ID":  "abc",
+				"X-Routing-ID":      "telegram:123",
+			},
+		},
+		{
+			name: "correlation and routing ids take precedence",
+			config: config.Config{
+				ForwardHeaderRename: map[string]string{"type": "X-Routing-ID"},
+			},
+			expected: map[string]string{
+				"X-Correlation-ID": "abc",
+				"X-Routing-ID":     "telegram:123",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapping, err := newHeaderMapping(tt.config)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, mapping.headers(context.Background(), message))
+		})
+	}
+}
+
+func TestHeaderMapping_Headers_GeneratedCorrelationID(t *testing.T) {
+	message := domain.Message{Key: "telegram:123", CorrelationID: "generated"}
+
+	mapping, err := newHeaderMapping(config.Config{})
+	require.NoError(t, err)
+	headers := mapping.headers(context.Background(), message)
+
+	assert.Equal(t, map[string]string{"X-Correlation-ID": "generated", "X-Routing-ID": "telegram:123"}, headers)
+}
+
+func TestHeaderMapping_Headers_MessageID(t *testing.T) {
+	message := domain.Message{Key: "telegram:123", Topic: "orders", ID: "1700000000000-0"}
+
+	mapping, err := newHeaderMapping(config.Config{ForwardMetadataHeaders: true})
+	require.NoError(t, err)
+	headers := mapping.headers(context.Background(), message)
+
+	assert.Equal(t, "1700000000000-0", headers[MessageIDHeader])
+}
+
+func TestValidHeaderName(t *testing.T) {
+	assert.True(t, validHeaderName("X-Kafka-Header-correlation_id"))
+	assert.False(t, validHeaderName(""))
+	assert.False(t, validHeaderName("user id"))
+	assert.False(t, validHeaderName("X-Header:"))
+	assert.False(t, validHeaderName("X-Héader"))
+}
+
+func TestNewHeaderMapping_InvalidPattern(t *testing.T) {
+	_, err := newHeaderMapping(config.Config{ForwardHeaders: []string{"type", "[trace"}})
+
+	assert.EqualError(t, err, "invalid header pattern [trace: syntax error in pattern")
+}
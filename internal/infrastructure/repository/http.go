@@ -4,44 +4,162 @@ import (
 	"anyker/config"
 	"anyker/internal/domain"
 	"anyker/internal/infrastructure/client"
+	"anyker/internal/metrics"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/rs/zerolog/log"
+	"io"
+	"net"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 // ForwardRepositoryImpl implements the domain.ForwardRepository interface using an HTTP client.
 type ForwardRepositoryImpl struct {
-	config     config.Config
-	httpClient client.HttpClient
+	config      config.Config
+	httpClient  client.HttpClient
+	retryPolicy retryPolicy
+	headers     headerMapping
+	// replyRepository publishes the responses of the API, nil to discard them
+	replyRepository domain.ReplyRepository
+
+	// health state reported by Ready
+	health forwardHealth
 }
 
 // NewForwardRepository creates a new ForwardRepositoryImpl.
+// The reply repository is optional, when nil the responses of the API are discarded.
+// It returns an error if the forward headers configuration is invalid.
 func NewForwardRepository(
 	config config.Config,
-	httpClient client.HttpClient) domain.ForwardRepository {
-	return &ForwardRepositoryImpl{
-		httpClient: httpClient,
-		config:     config,
+	httpClient client.HttpClient,
+	replyRepository domain.ReplyRepository) (domain.ForwardRepository, error) {
+	headers, err := newHeaderMapping(config)
+	if err != nil {
+		return nil, err
 	}
+	return &ForwardRepositoryImpl{
+		httpClient:      httpClient,
+		config:          config,
+		retryPolicy:     newRetryPolicy(config),
+		headers:         headers,
+		replyRepository: replyRepository,
+		health:          forwardHealth{maxFailures: config.HealthMaxForwardFailures},
+	}, nil
 }
 
 // Forward forwards a message to the configured API endpoint.
+// Failed attempts are retried according to the configured retry policy,
+// if all attempts fail a *domain.ForwardError is returned.
+// With a reply repository, the response of the API is published as a reply.
 func (f *ForwardRepositoryImpl) Forward(ctx context.Context, message domain.Message) error {
-	headers := map[string]string{
-		"X-Correlation-ID": string(message.Headers["correlation_id"]),
-		"X-Routing-ID":     message.Key,
+	headers := f.headers.headers(ctx, message)
+	start := time.Now()
+	defer func() {
+		metrics.ForwardDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	for attempt := 1; ; attempt++ {
+		statusCode, retryAfter, reply, err := f.post(ctx, headers, message)
+		if err == nil {
+			f.health.succeeded()
+			if reply != nil {
+				f.publishReply(ctx, *reply)
+			}
+			return nil
+		}
+		if attempt >= f.retryPolicy.maxAttempts || !f.retryPolicy.retryable(ctx, statusCode) {
+			return f.failed(statusCode, attempt, err)
+		}
+
+		delay := f.retryPolicy.delay(attempt, retryAfter)
+		log.Ctx(ctx).Warn().Err(err).Int("attempt", attempt).Dur("delay", delay).Msg("failed to forward message, retrying")
+
+		if sleepErr := sleep(ctx, delay); sleepErr != nil {
+			// shutting down, give up with the last error
+			return f.failed(statusCode, attempt, err)
+		}
 	}
+}
+
+// post makes a single forward attempt.
+// It returns the response status code (0 if no response was received), the Retry-After delay requested by the server
+// and, with a reply repository, the reply to publish once the message is forwarded.
+func (f *ForwardRepositoryImpl) post(ctx context.Context, headers map[string]string, message domain.Message) (int, time.Duration, *domain.Reply, error) {
 	resp, err := f.httpClient.Post(ctx, headers, message.Content, f.config.APIEndpoint)
 	if err != nil {
-		return err
+		return 0, 0, nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		return resp.StatusCode, retryAfter, nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	log.Ctx(ctx).Info().Msgf("API response status: %s", resp.Status)
+
+	if f.replyRepository == nil {
+		return resp.StatusCode, 0, nil, nil
+	}
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		// the message was delivered, it must not be forwarded again
+		log.Ctx(ctx).Error().Err(err).Msg("failed to read the API response, no reply is published")
+		return resp.StatusCode, 0, nil, nil
+	}
+	return resp.StatusCode, 0, &domain.Reply{
+		Message:     message,
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Content:     content,
+		NanobotName: f.config.NanobotName,
+		Timestamp:   time.Now(),
+	}, nil
+}
+
+// publishReply publishes the reply to a forwarded message.
+// A reply that can't be published is only logged, as failing would forward the message again.
+func (f *ForwardRepositoryImpl) publishReply(ctx context.Context, reply domain.Reply) {
+	if err := f.replyRepository.Publish(ctx, reply); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("failed to publish reply")
 	}
-	log.Info().Msgf("API response status: %s", resp.Status)
+}
 
+// Live always returns nil, the failures of the destination are reported by Ready.
+func (f *ForwardRepositoryImpl) Live() error {
 	return nil
 }
+
+// Ready returns an error when the number of consecutive forward failures reaches the configured maximum,
+// the forwarder does not need any setup before forwarding.
+func (f *ForwardRepositoryImpl) Ready() error {
+	return f.health.ready(f.config.APIEndpoint)
+}
+
+// failed counts a message that could not be forwarded and returns the error describing the failure.
+func (f *ForwardRepositoryImpl) failed(statusCode int, attempts int, err error) error {
+	metrics.ForwardFailures.WithLabelValues(strconv.Itoa(statusCode), errorClass(statusCode, err)).Inc()
+	f.health.failed(err)
+	return &domain.ForwardError{StatusCode: statusCode, Attempts: attempts, Err: err}
+}
+
+// errorClass classifies a forward failure for the metrics, keeping their cardinality low.
+func errorClass(statusCode int, err error) string {
+	var netErr net.Error
+	switch {
+	case statusCode >= 500:
+		return "server_error"
+	case statusCode >= 400:
+		return "client_error"
+	case statusCode != 0:
+		return "unexpected_status"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	default:
+		return "transport"
+	}
+}
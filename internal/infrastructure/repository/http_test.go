@@ -3,20 +3,31 @@ package repository
 import (
 	"anyker/config"
 	"anyker/internal/domain"
+	domainmocks "anyker/internal/domain/mocks"
+	"anyker/internal/infrastructure/client"
 	clientmocks "anyker/internal/infrastructure/client/mocks"
+	"anyker/internal/metrics"
 	"context"
 	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestForwardRepositoryImpl_Forward(t *testing.T) {
 	mockHTTPClient := new(clientmocks.MockHTTPClient)
 	cfg := config.Config{APIEndpoint: "http://localhost:8080"}
-	repo := NewForwardRepository(cfg, mockHTTPClient)
+	repo, err := NewForwardRepository(cfg, mockHTTPClient, nil)
+	require.NoError(t, err)
 
 	ctx := context.Background()
 	msg := domain.Message{Content: []byte(`{"key":"value"}`)}
@@ -38,18 +49,20 @@ func TestForwardRepositoryImpl_Forward(t *testing.T) {
 		err := repo.Forward(ctx, msg)
 
 		assert.Error(t, err)
-		assert.Equal(t, expectedErr, err)
+		assert.ErrorIs(t, err, expectedErr)
 		mockHTTPClient.AssertExpectations(t)
 	})
 
 	t.Run("unexpected status code", func(t *testing.T) {
 		mockResponse := clientmocks.CreateMockResponse(http.StatusInternalServerError, `{"error":"internal server error"}`)
 		mockHTTPClient.On("Post", ctx, mock.AnythingOfType("map[string]string"), msg.Content, cfg.APIEndpoint).Return(mockResponse, nil).Once()
+		failures := testutil.ToFloat64(metrics.ForwardFailures.WithLabelValues("500", "server_error"))
 
 		err := repo.Forward(ctx, msg)
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "unexpected status code: 500")
+		assert.Equal(t, failures+1, testutil.ToFloat64(metrics.ForwardFailures.WithLabelValues("500", "server_error")))
 		mockHTTPClient.AssertExpectations(t)
 	})
 
@@ -68,3 +81,258 @@ func TestForwardRepositoryImpl_Forward(t *testing.T) {
 		mockHTTPClient.AssertExpectations(t)
 	})
 }
+
+func TestForwardRepositoryImpl_Forward_Reply(t *testing.T) {
+	cfg := config.Config{APIEndpoint: "http://localhost:8080", NanobotName: "test-nanobot"}
+	ctx := context.Background()
+	msg := domain.Message{Content: []byte(`{"key":"value"}`), Key: "telegram:123", CorrelationID: "abc"}
+
+	t.Run("response published", func(t *testing.T) {
+		mockHTTPClient := new(clientmocks.MockHTTPClient)
+		mockReplyRepo := new(domainmocks.MockReplyRepository)
+		repo, err := NewForwardRepository(cfg, mockHTTPClient, mockReplyRepo)
+		require.NoError(t, err)
+
+		resp := clientmocks.CreateMockResponse(http.StatusOK, `{"text":"hello"}`)
+		resp.Header.Set("Content-Type", "application/json")
+		mockHTTPClient.On("Post", ctx, mock.Anything, msg.Content, cfg.APIEndpoint).Return(resp, nil).Once()
+		mockReplyRepo.On("Publish", ctx, mock.MatchedBy(func(reply domain.Reply) bool {
+			return reply.Message.Key == msg.Key && reply.Message.CorrelationID == "abc" &&
+				reply.StatusCode == http.StatusOK && reply.ContentType == "application/json" &&
+				string(reply.Content) == `{"text":"hello"}` && reply.NanobotName == "test-nanobot" &&
+				!reply.Timestamp.IsZero()
+		})).Return(nil).Once()
+
+		assert.NoError(t, repo.Forward(ctx, msg))
+		mockHTTPClient.AssertExpectations(t)
+		mockReplyRepo.AssertExpectations(t)
+	})
+
+	t.Run("publish error does not fail the forward", func(t *testing.T) {
+		mockHTTPClient := new(clientmocks.MockHTTPClient)
+		mockReplyRepo := new(domainmocks.MockReplyRepository)
+		repo, err := NewForwardRepository(cfg, mockHTTPClient, mockReplyRepo)
+		require.NoError(t, err)
+
+		mockHTTPClient.On("Post", ctx, mock.Anything, msg.Content, cfg.APIEndpoint).
+			Return(clientmocks.CreateMockResponse(http.StatusOK, `{}`), nil).Once()
+		mockReplyRepo.On("Publish", ctx, mock.Anything).Return(errors.New("broker down")).Once()
+
+		assert.NoError(t, repo.Forward(ctx, msg))
+		mockReplyRepo.AssertExpectations(t)
+	})
+
+	t.Run("no reply for failed forwards", func(t *testing.T) {
+		mockHTTPClient := new(clientmocks.MockHTTPClient)
+		mockReplyRepo := new(domainmocks.MockReplyRepository)
+		repo, err := NewForwardRepository(config.Config{APIEndpoint: cfg.APIEndpoint, ForwardRetryMaxAttempts: 1}, mockHTTPClient, mockReplyRepo)
+		require.NoError(t, err)
+
+		mockHTTPClient.On("Post", ctx, mock.Anything, msg.Content, cfg.APIEndpoint).
+			Return(clientmocks.CreateMockResponse(http.StatusBadRequest, `{"error":"invalid"}`), nil).Once()
+
+		assert.Error(t, repo.Forward(ctx, msg))
+		mockReplyRepo.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+	})
+}
+
+func TestForwardRepositoryImpl_Forward_Retry(t *testing.T) {
+	cfg := config.Config{
+		APIEndpoint:             "http://localhost:8080",
+		ForwardRetryMaxAttempts: 3,
+		ForwardRetryBaseDelay:   time.Millisecond,
+		ForwardRetryMaxDelay:    5 * time.Millisecond,
+		ForwardRetryStatusCodes: []int{http.StatusTooManyRequests, http.StatusServiceUnavailable},
+	}
+	ctx := context.Background()
+	msg := domain.Message{Content: []byte(`{"key":"value"}`)}
+
+	t.Run("retryable status code then success", func(t *testing.T) {
+		mockHTTPClient := new(clientmocks.MockHTTPClient)
+		repo, err := NewForwardRepository(cfg, mockHTTPClient, nil)
+		require.NoError(t, err)
+
+		mockHTTPClient.On("Post", ctx, mock.AnythingOfType("map[string]string"), msg.Content, cfg.APIEndpoint).
+			Return(clientmocks.CreateMockResponse(http.StatusServiceUnavailable, ""), nil).Once()
+		mockHTTPClient.On("Post", ctx, mock.AnythingOfType("map[string]string"), msg.Content, cfg.APIEndpoint).
+			Return(clientmocks.CreateMockResponse(http.StatusOK, `{"status":"ok"}`), nil).Once()
+
+		err = repo.Forward(ctx, msg)
+
+		assert.NoError(t, err)
+		mockHTTPClient.AssertExpectations(t)
+	})
+
+	t.Run("transport error then success", func(t *testing.T) {
+		mockHTTPClient := new(clientmocks.MockHTTPClient)
+		repo, err := NewForwardRepository(cfg, mockHTTPClient, nil)
+		require.NoError(t, err)
+
+		mockHTTPClient.On("Post", ctx, mock.AnythingOfType("map[string]string"), msg.Content, cfg.APIEndpoint).
+			Return(nil, errors.New("connection refused")).Once()
+		mockHTTPClient.On("Post", ctx, mock.AnythingOfType("map[string]string"), msg.Content, cfg.APIEndpoint).
+			Return(clientmocks.CreateMockResponse(http.StatusOK, `{"status":"ok"}`), nil).Once()
+
+		err = repo.Forward(ctx, msg)
+
+		assert.NoError(t, err)
+		mockHTTPClient.AssertExpectations(t)
+	})
+
+	t.Run("client timeout then success", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) == 1 {
+				// slow during a deploy
+				time.Sleep(200 * time.Millisecond)
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+		httpClient := &http.Client{Timeout: 50 * time.Millisecond}
+		serverCfg := cfg
+		serverCfg.APIEndpoint = server.URL
+		repo, err := NewForwardRepository(serverCfg, client.NewHttpClient(httpClient, nil, nil), nil)
+		require.NoError(t, err)
+
+		err = repo.Forward(ctx, msg)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("cancelled forward is not retried", func(t *testing.T) {
+		mockHTTPClient := new(clientmocks.MockHTTPClient)
+		repo, err := NewForwardRepository(cfg, mockHTTPClient, nil)
+		require.NoError(t, err)
+
+		cancelledCtx, cancel := context.WithCancel(ctx)
+		mockHTTPClient.On("Post", cancelledCtx, mock.AnythingOfType("map[string]string"), msg.Content, cfg.APIEndpoint).
+			Run(func(mock.Arguments) { cancel() }).
+			Return(nil, context.Canceled).Once()
+
+		err = repo.Forward(cancelledCtx, msg)
+
+		var forwardErr *domain.ForwardError
+		assert.ErrorAs(t, err, &forwardErr)
+		assert.Equal(t, 1, forwardErr.Attempts)
+		mockHTTPClient.AssertExpectations(t)
+	})
+
+	t.Run("non-retryable status code", func(t *testing.T) {
+		mockHTTPClient := new(clientmocks.MockHTTPClient)
+		repo, err := NewForwardRepository(cfg, mockHTTPClient, nil)
+		require.NoError(t, err)
+
+		mockHTTPClient.On("Post", ctx, mock.AnythingOfType("map[string]string"), msg.Content, cfg.APIEndpoint).
+			Return(clientmocks.CreateMockResponse(http.StatusBadRequest, ""), nil).Once()
+
+		err = repo.Forward(ctx, msg)
+
+		var forwardErr *domain.ForwardError
+		assert.ErrorAs(t, err, &forwardErr)
+		assert.Equal(t, http.StatusBadRequest, forwardErr.StatusCode)
+		assert.Equal(t, 1, forwardErr.Attempts)
+		mockHTTPClient.AssertExpectations(t)
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		mockHTTPClient := new(clientmocks.MockHTTPClient)
+		repo, err := NewForwardRepository(cfg, mockHTTPClient, nil)
+		require.NoError(t, err)
+
+		mockHTTPClient.On("Post", ctx, mock.AnythingOfType("map[string]string"), msg.Content, cfg.APIEndpoint).
+			Return(clientmocks.CreateMockResponse(http.StatusTooManyRequests, ""), nil).Times(3)
+
+		err = repo.Forward(ctx, msg)
+
+		var forwardErr *domain.ForwardError
+		assert.ErrorAs(t, err, &forwardErr)
+		assert.Equal(t, http.StatusTooManyRequests, forwardErr.StatusCode)
+		assert.Equal(t, 3, forwardErr.Attempts)
+		assert.Contains(t, err.Error(), "unexpected status code: 429")
+		mockHTTPClient.AssertExpectations(t)
+	})
+
+	t.Run("context cancelled while waiting", func(t *testing.T) {
+		mockHTTPClient := new(clientmocks.MockHTTPClient)
+		slowCfg := cfg
+		slowCfg.ForwardRetryBaseDelay = time.Minute
+		slowCfg.ForwardRetryMaxDelay = time.Minute
+		repo, err := NewForwardRepository(slowCfg, mockHTTPClient, nil)
+		require.NoError(t, err)
+
+		cancelledCtx, cancel := context.WithCancel(ctx)
+		mockHTTPClient.On("Post", cancelledCtx, mock.AnythingOfType("map[string]string"), msg.Content, cfg.APIEndpoint).
+			Run(func(mock.Arguments) { cancel() }).
+			Return(clientmocks.CreateMockResponse(http.StatusServiceUnavailable, ""), nil).Once()
+
+		err = repo.Forward(cancelledCtx, msg)
+
+		var forwardErr *domain.ForwardError
+		assert.ErrorAs(t, err, &forwardErr)
+		assert.Equal(t, 1, forwardErr.Attempts)
+		mockHTTPClient.AssertExpectations(t)
+	})
+}
+
+func TestForwardRepositoryImpl_Live(t *testing.T) {
+	mockHTTPClient := new(clientmocks.MockHTTPClient)
+	cfg := config.Config{
+		APIEndpoint:              "http://localhost:8080",
+		ForwardRetryMaxAttempts:  1,
+		HealthMaxForwardFailures: 2,
+	}
+	forwardRepo, err := NewForwardRepository(cfg, mockHTTPClient, nil)
+	require.NoError(t, err)
+	repo := forwardRepo.(*ForwardRepositoryImpl)
+
+	ctx := context.Background()
+	msg := domain.Message{Content: []byte(`{"key":"value"}`)}
+	fail := func() {
+		mockHTTPClient.On("Post", ctx, mock.AnythingOfType("map[string]string"), msg.Content, cfg.APIEndpoint).Return(nil, errors.New("connection refused")).Once()
+		assert.Error(t, repo.Forward(ctx, msg))
+	}
+
+	assert.NoError(t, repo.Live())
+	assert.NoError(t, repo.Ready())
+
+	fail()
+	assert.NoError(t, repo.Ready())
+	fail()
+	assert.EqualError(t, repo.Ready(), "2 consecutive failures forwarding to http://localhost:8080")
+	// a failing destination does not restart the worker
+	assert.NoError(t, repo.Live())
+
+	mockHTTPClient.On("Post", ctx, mock.AnythingOfType("map[string]string"), msg.Content, cfg.APIEndpoint).Return(clientmocks.CreateMockResponse(http.StatusOK, `{}`), nil).Once()
+	assert.NoError(t, repo.Forward(ctx, msg))
+	assert.NoError(t, repo.Ready())
+
+	fail()
+	fail()
+	assert.ErrorContains(t, repo.Ready(), "last success at")
+	mockHTTPClient.AssertExpectations(t)
+}
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		err        error
+		expected   string
+	}{
+		{name: "server error", statusCode: 503, err: errors.New("unexpected status code: 503"), expected: "server_error"},
+		{name: "client error", statusCode: 404, err: errors.New("unexpected status code: 404"), expected: "client_error"},
+		{name: "unexpected status", statusCode: 302, err: errors.New("unexpected status code: 302"), expected: "unexpected_status"},
+		{name: "canceled", err: context.Canceled, expected: "canceled"},
+		{name: "deadline exceeded", err: fmt.Errorf("post: %w", context.DeadlineExceeded), expected: "timeout"},
+		{name: "network timeout", err: &net.DNSError{IsTimeout: true}, expected: "timeout"},
+		{name: "transport", err: errors.New("connection refused"), expected: "transport"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, errorClass(tt.statusCode, tt.err))
+		})
+	}
+}
@@ -6,41 +6,158 @@ import (
 	"errors"
 	"fmt"
 	"github.com/rs/zerolog/log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"anyker/internal/domain"
+	"anyker/internal/metrics"
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// tracerName is the name of the tracer of the repositories.
+const tracerName = "anyker/internal/infrastructure/repository"
+
 // KafkaConsumer defines the interface for the Kafka consumer, to allow for mocking.
 type KafkaConsumer interface {
-	Subscribe(topic string, rebalanceCb kafka.RebalanceCb) error
+	SubscribeTopics(topics []string, rebalanceCb kafka.RebalanceCb) error
 	ReadMessage(timeout time.Duration) (*kafka.Message, error)
+	CommitOffsets(offsets []kafka.TopicPartition) ([]kafka.TopicPartition, error)
+	Assign(partitions []kafka.TopicPartition) error
+	IncrementalAssign(partitions []kafka.TopicPartition) error
+	GetRebalanceProtocol() string
+	OffsetsForTimes(times []kafka.TopicPartition, timeoutMs int) ([]kafka.TopicPartition, error)
+	GetWatermarkOffsets(topic string, partition int32) (low, high int64, err error)
+	Pause(partitions []kafka.TopicPartition) error
+	Resume(partitions []kafka.TopicPartition) error
 	Close() error
 }
 
+// KafkaProducer defines the interface for the Kafka producer, to allow for mocking.
+type KafkaProducer interface {
+	Produce(msg *kafka.Message, deliveryChan chan kafka.Event) error
+	Flush(timeoutMs int) int
+	Close()
+}
+
+// pausedPollInterval is how often Kafka is polled while paused and waiting for a worker, well below the
+// max.poll.interval.ms after which the consumer is evicted from the group.
+const pausedPollInterval = time.Second
+
+// maxPendingOffsets is the number of messages in flight of a partition above which the consumer is not live.
+// Once a message fails without a dead-letter topic, the offsets of its partition are no longer committed,
+// so the consumer is restarted to consume again from the last committed offset.
+const maxPendingOffsets = 10000
+
+// offsetsForTimesTimeoutMs is how long to wait for the broker to look up the offsets of the start timestamp.
+const offsetsForTimesTimeoutMs = 10000
+
+// partitionKey identifies a Kafka partition.
+type partitionKey struct {
+	topic     string
+	partition int32
+}
+
 // Consumer is a Kafka consumer that implements the ConsumerRepository interface.
 type Consumer struct {
 	consumer KafkaConsumer
-	topic    string
+	// topics are topic names or regular expressions starting with ^
+	topics []string
+
+	// replay mode, see config.Config, a nil startOffset disables the replay from an explicit offset
+	startTimestamp time.Time
+	startOffset    *kafka.Offset
+	// replayed holds the partitions already moved to the start position,
+	// so the replay only happens once per partition and later rebalances resume from the committed offsets
+	replayed map[partitionKey]bool
+
+	// tracker holds the messages in flight, as they may be committed out of order
+	tracker offsetTracker
+	// maxPendingOffsets is the number of messages in flight of a partition that fails Live, 0 for no limit
+	maxPendingOffsets int
+	// maxUncommittedAge is how long after a message failed its partition may stay uncommitted, 0 for no limit
+	maxUncommittedAge time.Duration
+	// commitMu serializes the commits, so a commit never moves the offset of a partition backwards
+	commitMu sync.Mutex
+
+	// health state reported by Live and Ready
+	stallTimeout time.Duration
+	subscribed   atomic.Bool
+	// lastPoll is the time of the last poll in Unix nanoseconds, 0 before the first one
+	lastPoll     atomic.Int64
+	assignmentMu sync.Mutex
+	assignment   map[partitionKey]bool
+	// assignmentVersion changes with every assignment, so a pause is applied to the newly assigned partitions too
+	assignmentVersion uint64
+
+	// paused is requested by Pause and Resume, and applied by the consume loop as the Kafka client is not
+	// meant to be used concurrently, which is woken up by pauseRequested while waiting for a worker.
+	// pauseApplied and pausedVersion are only used by the consume loop.
+	paused         atomic.Bool
+	pauseRequested chan struct{}
+	pauseApplied   bool
+	pausedVersion  uint64
 }
 
 // NewConsumer creates a new Kafka consumer.
 func NewConsumer(config config.Config) (domain.ConsumerRepository, error) {
-	c, err := kafka.NewConsumer(&kafka.ConfigMap{
-		"bootstrap.servers": config.KafkaBroker,
-		"group.id":          config.KafkaGroupID,
-		"auto.offset.reset": "latest",
+	var startTimestamp time.Time
+	if config.KafkaStartTimestamp != "" {
+		timestamp, err := time.Parse(time.RFC3339, config.KafkaStartTimestamp)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start timestamp: %w", err)
+		}
+		startTimestamp = timestamp
+	}
+	var startOffset *kafka.Offset
+	if config.KafkaStartOffset != "" {
+		offset, err := kafka.NewOffset(config.KafkaStartOffset)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start offset: %w", err)
+		}
+		startOffset = &offset
+	}
+	topics, err := parseTopics(config.KafkaTopic)
+	if err != nil {
+		return nil, err
+	}
+	autoOffsetReset := config.KafkaAutoOffsetReset
+	if autoOffsetReset == "" {
+		autoOffsetReset = "latest"
+	}
+
+	configMap, err := newKafkaConfigMap(config, kafka.ConfigMap{
+		"group.id": config.KafkaGroupID,
 		// latest to ignore old messages, earliest for the opposite
-		// TODO parameterize the offset
+		"auto.offset.reset": autoOffsetReset,
+		// offsets are committed by Commit once the message has been forwarded
+		"enable.auto.commit": false,
 	})
+	if err != nil {
+		return nil, err
+	}
+	c, err := kafka.NewConsumer(configMap)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create consumer: %w", err)
 	}
 
 	return &Consumer{
-		consumer: c,
-		topic:    config.KafkaTopic,
+		consumer:          c,
+		topics:            topics,
+		startTimestamp:    startTimestamp,
+		startOffset:       startOffset,
+		replayed:          make(map[partitionKey]bool),
+		maxPendingOffsets: maxPendingOffsets,
+		maxUncommittedAge: config.HealthMaxUncommittedAge,
+		stallTimeout:      config.HealthStallTimeout,
+		pauseRequested:    make(chan struct{}, 1),
 	}, nil
 }
 
@@ -48,46 +165,495 @@ func NewConsumer(config config.Config) (domain.ConsumerRepository, error) {
 func (c *Consumer) Consume(ctx context.Context, messages chan<- *domain.Message) error {
 	defer close(messages)
 
-	err := c.consumer.Subscribe(c.topic, nil)
+	err := c.consumer.SubscribeTopics(c.topics, c.rebalance)
 	if err != nil {
-		return fmt.Errorf("failed to subscribe to topic: %w", err)
+		return fmt.Errorf("failed to subscribe to topics %v: %w", c.topics, err)
 	}
+	c.subscribed.Store(true)
+	defer c.subscribed.Store(false)
 
+	// pending holds the messages polled while waiting for a worker, sent before polling again
+	var pending []*domain.Message
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
 		default:
-			msg, err := c.consumer.ReadMessage(1000 * time.Millisecond)
-			if err != nil {
-				if kafkaErr, ok := err.(kafka.Error); ok && kafkaErr.Code() == kafka.ErrTimedOut {
-					continue
+			if len(pending) > 0 {
+				message := pending[0]
+				pending = pending[1:]
+				polled, err := c.send(ctx, messages, message)
+				if err != nil {
+					return err
 				}
+				pending = append(pending, polled...)
+				continue
+			}
+			c.applyPause()
+			message, err := c.poll(ctx, 1000*time.Millisecond)
+			if err != nil {
 				if errors.Is(err, context.Canceled) {
 					return nil // Graceful exit on context cancellation
 				}
-				return fmt.Errorf("failed to read message: %w", err)
+				return err
+			}
+			if message != nil {
+				pending = append(pending, message)
 			}
-			headers := make(map[string]string)
-			if msg.Headers != nil {
-				for _, h := range msg.Headers {
-					headers[h.Key] = string(h.Value)
+		}
+	}
+}
+
+// poll reads a message from Kafka, nil when none arrived within the timeout.
+func (c *Consumer) poll(ctx context.Context, timeout time.Duration) (*domain.Message, error) {
+	pollStart := time.Now()
+	msg, err := c.consumer.ReadMessage(timeout)
+	c.lastPoll.Store(time.Now().UnixNano())
+	if err != nil {
+		if kafkaErr, ok := err.(kafka.Error); ok && kafkaErr.Code() == kafka.ErrTimedOut {
+			return nil, nil
+		}
+		if errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to read message: %w", err)
+	}
+	headers := make(map[string]string)
+	if msg.Headers != nil {
+		for _, h := range msg.Headers {
+			headers[h.Key] = string(h.Value)
+		}
+	}
+	log.Debug().Msgf("headers received from Kafka message %v", headers)
+	log.Debug().Msgf("key receive from Kafka message %v", string(msg.Key))
+	log.Debug().Msgf("payload receive from Kafka message %v", string(msg.Value))
+
+	message := &domain.Message{
+		Content:   msg.Value,
+		Headers:   headers,
+		Key:       string(msg.Key),
+		Partition: msg.TopicPartition.Partition,
+		Offset:    int64(msg.TopicPartition.Offset),
+		Timestamp: msg.Timestamp,
+	}
+	if msg.TopicPartition.Topic != nil {
+		message.Topic = *msg.TopicPartition.Topic
+	}
+	metrics.MessagesConsumed.WithLabelValues(message.Topic).Inc()
+	tracePoll(ctx, message, pollStart)
+	c.updateLag(message)
+	c.tracker.track(partitionKey{topic: message.Topic, partition: message.Partition}, message.Offset)
+	return message, nil
+}
+
+// send sends a message to the workers. While all of them are busy, e.g. waiting for the circuit breaker,
+// a Pause or Resume is applied right away and, once paused, Kafka is still polled every pausedPollInterval
+// so the consumer is not evicted from the group. The messages polled meanwhile, only from partitions assigned
+// since the last pause, are returned to be sent next.
+func (c *Consumer) send(ctx context.Context, messages chan<- *domain.Message, message *domain.Message) ([]*domain.Message, error) {
+	ticker := time.NewTicker(pausedPollInterval)
+	defer ticker.Stop()
+	var polled []*domain.Message
+	for {
+		select {
+		case messages <- message:
+			return polled, nil
+		case <-ctx.Done():
+			return polled, nil
+		case <-c.pauseRequested:
+			c.applyPause()
+		case <-ticker.C:
+			if !c.pauseApplied {
+				continue
+			}
+			next, err := c.poll(ctx, 0)
+			if err != nil {
+				if errors.Is(err, context.Canceled) {
+					return polled, nil
 				}
+				return polled, err
 			}
-			log.Debug().Msgf("headers received from Kafka message %v", headers)
-			log.Debug().Msgf("key receive from Kafka message %v", string(msg.Key))
-			log.Debug().Msgf("payload receive from Kafka message %v", string(msg.Value))
-
-			messages <- &domain.Message{
-				Content: msg.Value,
-				Headers: headers,
-				Key:     string(msg.Key),
+			if next != nil {
+				polled = append(polled, next)
 			}
+			// partitions assigned by the poll are paused too
+			c.applyPause()
 		}
 	}
 }
 
+// tracePoll records the span of the poll that returned a message, as a child of the trace context of its headers.
+// Only the Kafka consumer polls, the other sources have no poll span, so its messaging system is always Kafka.
+// The message is forwarded in another span with the same parent, so a slow poll is told apart from a slow forward.
+func tracePoll(ctx context.Context, message *domain.Message, start time.Time) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(message.Headers))
+	_, span := otel.Tracer(tracerName).Start(ctx, "poll "+message.Topic,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithTimestamp(start),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingOperationTypeReceive,
+			semconv.MessagingDestinationName(message.Topic),
+			semconv.MessagingDestinationPartitionID(strconv.Itoa(int(message.Partition))),
+			semconv.MessagingKafkaMessageOffset(int(message.Offset)),
+			semconv.MessagingKafkaMessageKey(message.Key),
+		))
+	span.End()
+}
+
+// updateLag updates the consumer lag of the partition of a message that has just been consumed.
+// The high watermark is the one cached from the last fetch, so no request is made to the broker.
+func (c *Consumer) updateLag(message *domain.Message) {
+	_, high, err := c.consumer.GetWatermarkOffsets(message.Topic, message.Partition)
+	if err != nil || high < 0 {
+		return
+	}
+	lag := high - message.Offset - 1
+	if lag < 0 {
+		lag = 0
+	}
+	metrics.ConsumerLag.WithLabelValues(message.Topic, strconv.Itoa(int(message.Partition))).Set(float64(lag))
+}
+
+// parseTopics splits a comma-separated list of topics.
+// Entries starting with ^ are regular expressions matching topic names, they are validated here to fail fast.
+func parseTopics(value string) ([]string, error) {
+	var topics []string
+	for _, topic := range strings.Split(value, ",") {
+		topic = strings.TrimSpace(topic)
+		if topic == "" {
+			continue
+		}
+		if strings.HasPrefix(topic, "^") {
+			if _, err := regexp.Compile(topic); err != nil {
+				return nil, fmt.Errorf("invalid topic pattern %s: %w", topic, err)
+			}
+		}
+		topics = append(topics, topic)
+	}
+	if len(topics) == 0 {
+		return nil, errors.New("no topic to subscribe to")
+	}
+	return topics, nil
+}
+
+// matchesTopics reports whether a topic is one of the topics, or matches one of their regular expressions.
+func matchesTopics(topics []string, topic string) bool {
+	for _, t := range topics {
+		if t == topic {
+			return true
+		}
+		if strings.HasPrefix(t, "^") {
+			if matched, _ := regexp.MatchString(t, topic); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rebalance handles partition assignments, moving newly assigned partitions to the start position in replay mode,
+// and drops the messages in flight of revoked partitions, their new owner will consume them again.
+// Assignments not changed here are left to the default handling of the Kafka client.
+func (c *Consumer) rebalance(_ *kafka.Consumer, event kafka.Event) error {
+	c.updateAssignment(event)
+
+	if revoked, ok := event.(kafka.RevokedPartitions); ok {
+		for _, tp := range revoked.Partitions {
+			if tp.Topic != nil {
+				c.tracker.forget(partitionKey{topic: *tp.Topic, partition: tp.Partition})
+				metrics.ConsumerLag.DeleteLabelValues(*tp.Topic, strconv.Itoa(int(tp.Partition)))
+			}
+		}
+		return nil
+	}
+
+	assigned, ok := event.(kafka.AssignedPartitions)
+	if !ok || (c.startTimestamp.IsZero() && c.startOffset == nil) {
+		return nil
+	}
+
+	var toReplay []kafka.TopicPartition
+	for _, tp := range assigned.Partitions {
+		if tp.Topic != nil && !c.replayed[partitionKey{topic: *tp.Topic, partition: tp.Partition}] {
+			toReplay = append(toReplay, tp)
+		}
+	}
+	if len(toReplay) == 0 {
+		return nil
+	}
+	if c.replayed == nil {
+		c.replayed = make(map[partitionKey]bool)
+	}
+
+	startOffsets, err := c.startOffsets(toReplay)
+	if err != nil {
+		return err
+	}
+	for _, tp := range startOffsets {
+		log.Info().Msgf("replaying %s[%d] from offset %v", *tp.Topic, tp.Partition, tp.Offset)
+		c.replayed[partitionKey{topic: *tp.Topic, partition: tp.Partition}] = true
+	}
+
+	// partitions already replayed keep their committed offsets
+	partitions := make([]kafka.TopicPartition, 0, len(assigned.Partitions))
+	partitions = append(partitions, startOffsets...)
+	for _, tp := range assigned.Partitions {
+		if tp.Topic != nil && !containsPartition(startOffsets, tp) {
+			partitions = append(partitions, tp)
+		}
+	}
+
+	if c.consumer.GetRebalanceProtocol() == "COOPERATIVE" {
+		err = c.consumer.IncrementalAssign(partitions)
+	} else {
+		err = c.consumer.Assign(partitions)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to assign partitions: %w", err)
+	}
+	return nil
+}
+
+// updateAssignment keeps track of the partitions assigned to the consumer.
+// Assignments are incremental with the cooperative protocol, and the eager protocol revokes every partition
+// before assigning the new ones, so adding and removing the partitions of each event works for both.
+func (c *Consumer) updateAssignment(event kafka.Event) {
+	c.assignmentMu.Lock()
+	defer c.assignmentMu.Unlock()
+
+	if c.assignment == nil {
+		c.assignment = make(map[partitionKey]bool)
+	}
+	c.assignmentVersion++
+	switch e := event.(type) {
+	case kafka.AssignedPartitions:
+		for _, tp := range e.Partitions {
+			if tp.Topic != nil {
+				c.assignment[partitionKey{topic: *tp.Topic, partition: tp.Partition}] = true
+			}
+		}
+	case kafka.RevokedPartitions:
+		for _, tp := range e.Partitions {
+			if tp.Topic != nil {
+				delete(c.assignment, partitionKey{topic: *tp.Topic, partition: tp.Partition})
+			}
+		}
+	}
+}
+
+// Live returns an error when the consumer has stopped polling Kafka for longer than the stall timeout,
+// which happens when it is blocked waiting for the workers to take its messages, or when the offsets of a partition
+// have not been committed for too many messages or for too long since a message failed.
+func (c *Consumer) Live() error {
+	if stuck, ok := c.tracker.stuck(c.maxPendingOffsets, c.maxUncommittedAge, time.Now()); ok {
+		err := fmt.Errorf("kafka consumer has %d uncommitted messages in %s[%d] from offset %d",
+			stuck.pending, stuck.key.topic, stuck.key.partition, stuck.offset)
+		if stuck.failed {
+			err = fmt.Errorf("%w, offset %d failed %s ago", err, stuck.failedOffset, stuck.age.Round(time.Second))
+		}
+		return err
+	}
+	lastPoll := c.lastPoll.Load()
+	if lastPoll == 0 || c.stallTimeout <= 0 {
+		// not started yet
+		return nil
+	}
+	if c.paused.Load() {
+		// waiting for the downstream service to recover, see Pause
+		return nil
+	}
+	if since := time.Since(time.Unix(0, lastPoll)); since > c.stallTimeout {
+		return fmt.Errorf("kafka consumer has not polled for %s", since.Round(time.Second))
+	}
+	return nil
+}
+
+// Ready returns an error until the consumer is subscribed, has polled Kafka and has partitions assigned.
+func (c *Consumer) Ready() error {
+	if !c.subscribed.Load() {
+		return errors.New("kafka consumer is not subscribed")
+	}
+	if c.lastPoll.Load() == 0 {
+		return errors.New("kafka consumer has not polled yet")
+	}
+	c.assignmentMu.Lock()
+	assigned := len(c.assignment)
+	c.assignmentMu.Unlock()
+	if assigned == 0 {
+		return errors.New("kafka consumer has no partition assigned")
+	}
+	return nil
+}
+
+// Pause stops fetching messages from the assigned partitions, while still polling Kafka to stay in the group.
+// Messages already consumed are still delivered.
+func (c *Consumer) Pause() {
+	if !c.paused.Swap(true) {
+		log.Warn().Msg("pausing Kafka consumption")
+		c.requestPause()
+	}
+}
+
+// Resume fetches messages again from the assigned partitions after a Pause.
+func (c *Consumer) Resume() {
+	if c.paused.Swap(false) {
+		log.Info().Msg("resuming Kafka consumption")
+		c.requestPause()
+	}
+}
+
+// requestPause wakes up the consume loop waiting for a worker to apply the pause, see send.
+func (c *Consumer) requestPause() {
+	select {
+	case c.pauseRequested <- struct{}{}:
+	default:
+		// already requested
+	}
+}
+
+// applyPause pauses or resumes the assigned partitions when requested by Pause or Resume,
+// and pauses the partitions assigned while paused.
+func (c *Consumer) applyPause() {
+	paused := c.paused.Load()
+
+	c.assignmentMu.Lock()
+	version := c.assignmentVersion
+	if paused == c.pauseApplied && (!paused || version == c.pausedVersion) {
+		c.assignmentMu.Unlock()
+		return
+	}
+	partitions := make([]kafka.TopicPartition, 0, len(c.assignment))
+	for key := range c.assignment {
+		topic := key.topic
+		partitions = append(partitions, kafka.TopicPartition{Topic: &topic, Partition: key.partition})
+	}
+	c.assignmentMu.Unlock()
+
+	var err error
+	if paused {
+		err = c.consumer.Pause(partitions)
+	} else {
+		err = c.consumer.Resume(partitions)
+	}
+	if err != nil {
+		// retried on the next poll
+		log.Error().Err(err).Bool("paused", paused).Msg("failed to pause or resume partitions")
+		return
+	}
+	c.pauseApplied = paused
+	c.pausedVersion = version
+}
+
+// startOffsets returns the given partitions with their offset set to the replay start position.
+func (c *Consumer) startOffsets(partitions []kafka.TopicPartition) ([]kafka.TopicPartition, error) {
+	startOffsets := make([]kafka.TopicPartition, len(partitions))
+	for i, tp := range partitions {
+		startOffsets[i] = kafka.TopicPartition{Topic: tp.Topic, Partition: tp.Partition}
+		if c.startTimestamp.IsZero() {
+			startOffsets[i].Offset = *c.startOffset
+		} else {
+			// OffsetsForTimes expects the timestamp in the offset field
+			startOffsets[i].Offset = kafka.Offset(c.startTimestamp.UnixMilli())
+		}
+	}
+	if c.startTimestamp.IsZero() {
+		return startOffsets, nil
+	}
+
+	startOffsets, err := c.consumer.OffsetsForTimes(startOffsets, offsetsForTimesTimeoutMs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get offsets for start timestamp: %w", err)
+	}
+	for _, tp := range startOffsets {
+		if tp.Error != nil {
+			return nil, fmt.Errorf("failed to get offset for start timestamp of %s[%d]: %w", *tp.Topic, tp.Partition, tp.Error)
+		}
+	}
+	return startOffsets, nil
+}
+
+// containsPartition reports whether the partition is in the list, regardless of its offset.
+func containsPartition(partitions []kafka.TopicPartition, tp kafka.TopicPartition) bool {
+	for _, p := range partitions {
+		if *p.Topic == *tp.Topic && p.Partition == tp.Partition {
+			return true
+		}
+	}
+	return false
+}
+
+// Commit marks the message as processed and commits the offset following the last message of its partition
+// processed without gaps, so consumption resumes after it and no message still in flight is skipped.
+func (c *Consumer) Commit(_ context.Context, message domain.Message) error {
+	c.commitMu.Lock()
+	defer c.commitMu.Unlock()
+
+	offset, ok := c.tracker.markDone(partitionKey{topic: message.Topic, partition: message.Partition}, message.Offset)
+	if !ok {
+		// previous messages of the partition are still in flight
+		return nil
+	}
+
+	_, err := c.consumer.CommitOffsets([]kafka.TopicPartition{{
+		Topic:     &message.Topic,
+		Partition: message.Partition,
+		Offset:    kafka.Offset(offset),
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to commit offset: %w", err)
+	}
+	return nil
+}
+
+// Reject records that a message could not be processed, e.g. without a dead-letter topic. Kafka has no way to
+// give it back, so the offsets of its partition are not committed anymore, until Live fails and the restarted
+// consumer consumes it again from the last committed offset.
+func (c *Consumer) Reject(_ context.Context, message domain.Message) error {
+	if c.tracker.fail(partitionKey{topic: message.Topic, partition: message.Partition}, message.Offset, time.Now()) {
+		log.Warn().Msgf("offsets of %s[%d] are not committed anymore from offset %d, which could not be processed",
+			message.Topic, message.Partition, message.Offset)
+	}
+	return nil
+}
+
 // Close closes the Kafka consumer.
 func (c *Consumer) Close() error {
 	return c.consumer.Close()
 }
+
+// produce produces a message and waits for its delivery report or until the context is done.
+func produce(ctx context.Context, producer KafkaProducer, msg *kafka.Message) error {
+	deliveryChan := make(chan kafka.Event, 1)
+	if err := producer.Produce(msg, deliveryChan); err != nil {
+		return fmt.Errorf("failed to produce message: %w", err)
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case event := <-deliveryChan:
+		delivered, ok := event.(*kafka.Message)
+		if !ok {
+			return fmt.Errorf("unexpected delivery event: %v", event)
+		}
+		if delivered.TopicPartition.Error != nil {
+			return fmt.Errorf("failed to deliver message: %w", delivered.TopicPartition.Error)
+		}
+		return nil
+	}
+}
+
+// logProducerEvents logs the errors reported by a producer outside of the delivery reports, e.g. when the brokers
+// are down or the authentication fails, until the producer is closed. The events must be read, otherwise
+// their channel fills up and the producer stops reporting them.
+func logProducerEvents(name string, events <-chan kafka.Event) {
+	for event := range events {
+		switch e := event.(type) {
+		case kafka.Error:
+			log.Error().Err(e).Msgf("%s producer error", name)
+		default:
+			log.Debug().Msgf("%s producer event: %v", name, e)
+		}
+	}
+}
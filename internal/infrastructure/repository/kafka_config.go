@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"anyker/config"
+	"errors"
+	"strings"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// newKafkaConfigMap creates the librdkafka configuration shared by the consumer and the producers:
+// the brokers, the security settings and the properties passed through, with the given properties on top.
+func newKafkaConfigMap(config config.Config, properties kafka.ConfigMap) (*kafka.ConfigMap, error) {
+	configMap := kafka.ConfigMap{
+		"bootstrap.servers": config.KafkaBroker,
+	}
+	settings := map[string]string{
+		"security.protocol":        config.KafkaSecurityProtocol,
+		"sasl.mechanism":           config.KafkaSASLMechanism,
+		"sasl.username":            config.KafkaSASLUsername,
+		"sasl.password":            config.KafkaSASLPassword,
+		"ssl.ca.location":          config.KafkaSSLCALocation,
+		"ssl.certificate.location": config.KafkaSSLCertificateLocation,
+		"ssl.key.location":         config.KafkaSSLKeyLocation,
+		"ssl.key.password":         config.KafkaSSLKeyPassword,
+	}
+
+	if strings.EqualFold(config.KafkaSASLMechanism, "OAUTHBEARER") {
+		// the consume loop does not handle token refresh events, so the tokens are fetched by librdkafka
+		if config.KafkaOAuthBearerTokenEndpointURL != "" {
+			settings["sasl.oauthbearer.method"] = "oidc"
+			settings["sasl.oauthbearer.token.endpoint.url"] = config.KafkaOAuthBearerTokenEndpointURL
+			settings["sasl.oauthbearer.client.id"] = config.KafkaOAuthBearerClientID
+			settings["sasl.oauthbearer.client.secret"] = config.KafkaOAuthBearerClientSecret
+			settings["sasl.oauthbearer.scope"] = config.KafkaOAuthBearerScope
+		} else if config.KafkaProperties["enable.sasl.oauthbearer.unsecure.jwt"] != "true" {
+			return nil, errors.New("the OAUTHBEARER mechanism requires a token endpoint URL")
+		}
+	}
+
+	for key, value := range settings {
+		if value != "" {
+			configMap[key] = value
+		}
+	}
+	for key, value := range config.KafkaProperties {
+		configMap[key] = value
+	}
+	for key, value := range properties {
+		configMap[key] = value
+	}
+	return &configMap, nil
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"anyker/config"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewKafkaConfigMap(t *testing.T) {
+	t.Run("plaintext", func(t *testing.T) {
+		configMap, err := newKafkaConfigMap(config.Config{KafkaBroker: "localhost:9092"}, kafka.ConfigMap{"group.id": "test-group"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, &kafka.ConfigMap{"bootstrap.servers": "localhost:9092", "group.id": "test-group"}, configMap)
+	})
+
+	t.Run("SASL SCRAM over SSL", func(t *testing.T) {
+		configMap, err := newKafkaConfigMap(config.Config{
+			KafkaBroker:           "kafka:9093",
+			KafkaSecurityProtocol: "SASL_SSL",
+			KafkaSASLMechanism:    "SCRAM-SHA-512",
+			KafkaSASLUsername:     "anyker",
+			KafkaSASLPassword:     "secret",
+			KafkaSSLCALocation:    "/etc/kafka/ca.pem",
+		}, nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &kafka.ConfigMap{
+			"bootstrap.servers": "kafka:9093",
+			"security.protocol": "SASL_SSL",
+			"sasl.mechanism":    "SCRAM-SHA-512",
+			"sasl.username":     "anyker",
+			"sasl.password":     "secret",
+			"ssl.ca.location":   "/etc/kafka/ca.pem",
+		}, configMap)
+	})
+
+	t.Run("SSL client certificate", func(t *testing.T) {
+		configMap, err := newKafkaConfigMap(config.Config{
+			KafkaBroker:                 "kafka:9093",
+			KafkaSecurityProtocol:       "SSL",
+			KafkaSSLCertificateLocation: "/etc/kafka/client.pem",
+			KafkaSSLKeyLocation:         "/etc/kafka/client.key",
+			KafkaSSLKeyPassword:         "key-secret",
+		}, nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &kafka.ConfigMap{
+			"bootstrap.servers":        "kafka:9093",
+			"security.protocol":        "SSL",
+			"ssl.certificate.location": "/etc/kafka/client.pem",
+			"ssl.key.location":         "/etc/kafka/client.key",
+			"ssl.key.password":         "key-secret",
+		}, configMap)
+	})
+
+	t.Run("OAUTHBEARER", func(t *testing.T) {
+		configMap, err := newKafkaConfigMap(config.Config{
+			KafkaBroker:                      "kafka:9093",
+			KafkaSecurityProtocol:            "SASL_SSL",
+			KafkaSASLMechanism:               "OAUTHBEARER",
+			KafkaOAuthBearerTokenEndpointURL: "https://auth.example.com/token",
+			KafkaOAuthBearerClientID:         "anyker",
+			KafkaOAuthBearerClientSecret:     "secret",
+		}, nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &kafka.ConfigMap{
+			"bootstrap.servers":                   "kafka:9093",
+			"security.protocol":                   "SASL_SSL",
+			"sasl.mechanism":                      "OAUTHBEARER",
+			"sasl.oauthbearer.method":             "oidc",
+			"sasl.oauthbearer.token.endpoint.url": "https://auth.example.com/token",
+			"sasl.oauthbearer.client.id":          "anyker",
+			"sasl.oauthbearer.client.secret":      "secret",
+		}, configMap)
+	})
+
+	t.Run("OAUTHBEARER without token endpoint", func(t *testing.T) {
+		_, err := newKafkaConfigMap(config.Config{KafkaSASLMechanism: "OAUTHBEARER"}, nil)
+
+		assert.EqualError(t, err, "the OAUTHBEARER mechanism requires a token endpoint URL")
+	})
+
+	t.Run("passthrough properties", func(t *testing.T) {
+		configMap, err := newKafkaConfigMap(config.Config{
+			KafkaBroker: "localhost:9092",
+			KafkaProperties: map[string]string{
+				"socket.keepalive.enable": "true",
+				"enable.auto.commit":      "true",
+			},
+		}, kafka.ConfigMap{"enable.auto.commit": false})
+
+		assert.NoError(t, err)
+		assert.Equal(t, &kafka.ConfigMap{
+			"bootstrap.servers":       "localhost:9092",
+			"socket.keepalive.enable": "true",
+			// the properties of the component take precedence
+			"enable.auto.commit": false,
+		}, configMap)
+	})
+}
+
+func TestNewConsumer_InvalidProperty(t *testing.T) {
+	_, err := NewConsumer(config.Config{
+		KafkaBroker:     "localhost:9092",
+		KafkaGroupID:    "test-group",
+		KafkaTopic:      "test-topic",
+		KafkaProperties: map[string]string{"not.a.property": "true"},
+	})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to create consumer")
+}
@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"anyker/config"
+	"anyker/internal/domain"
+	"anyker/internal/metrics"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Placeholders of the sink topic.
+const (
+	sinkTopicOrigin = "{origin}"
+	sinkTopicTopic  = "{topic}"
+)
+
+// KafkaSink implements the domain.ForwardRepository interface by producing the messages to a Kafka topic,
+// of the source cluster or another one, with their key, headers and timestamp.
+type KafkaSink struct {
+	producer KafkaProducer
+	// topic of the produced messages, with the placeholders replaced for each message
+	topic  string
+	broker string
+	// echoCorrelationID adds the generated correlation IDs to the headers of the produced messages
+	echoCorrelationID bool
+	// consumedTopics are the topics consumed by anyker when producing to the source cluster, nil otherwise,
+	// the messages are not produced to them or they would be consumed again
+	consumedTopics []string
+
+	// health state reported by Ready
+	health forwardHealth
+}
+
+// NewKafkaSink creates a new KafkaSink producing to the given topic, the configured one if empty.
+// Without SinkKafkaBroker the producer connects to the source cluster with its settings, and the sink properties on top,
+// otherwise only the sink properties are used. The topic must not be consumed when bridging a Kafka topic to the same cluster,
+// else the messages would be consumed again: a topic without placeholders is checked here, the others for each message.
+func NewKafkaSink(cfg config.Config, topic string) (domain.ForwardRepository, error) {
+	if topic == "" {
+		topic = cfg.SinkKafkaTopic
+	}
+	// the order of the messages of a key is kept when the producer retries
+	properties := kafka.ConfigMap{"enable.idempotence": true}
+	for key, value := range cfg.SinkKafkaProperties {
+		properties[key] = value
+	}
+
+	broker := cfg.SinkKafkaBroker
+	var configMap *kafka.ConfigMap
+	var consumedTopics []string
+	if broker == "" {
+		var err error
+		if cfg.Source() == config.SourceKafka {
+			consumedTopics, err = parseTopics(cfg.KafkaTopic)
+			if err != nil {
+				return nil, err
+			}
+			if topic == sinkTopicTopic || matchesTopics(consumedTopics, topic) {
+				return nil, fmt.Errorf("the sink topic %s must differ from the consumed ones when producing to the source cluster", topic)
+			}
+		}
+		configMap, err = newKafkaConfigMap(cfg, properties)
+		if err != nil {
+			return nil, err
+		}
+		broker = cfg.KafkaBroker
+	} else {
+		properties["bootstrap.servers"] = broker
+		configMap = &properties
+	}
+
+	p, err := kafka.NewProducer(configMap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sink producer: %w", err)
+	}
+	go logProducerEvents("sink", p.Events())
+	sink := newKafkaSink(cfg, p, topic, broker)
+	sink.consumedTopics = consumedTopics
+	return sink, nil
+}
+
+// newKafkaSink creates a KafkaSink producing with the given producer.
+func newKafkaSink(config config.Config, producer KafkaProducer, topic, broker string) *KafkaSink {
+	return &KafkaSink{
+		producer:          producer,
+		topic:             topic,
+		broker:            broker,
+		echoCorrelationID: config.CorrelationIDEcho,
+		health:            forwardHealth{maxFailures: config.HealthMaxForwardFailures},
+	}
+}
+
+// Forward produces a message to the sink topic and waits until it has been delivered.
+// The producer retries on its own until the message times out, so a failure returns a *domain.ForwardError of one attempt.
+func (s *KafkaSink) Forward(ctx context.Context, message domain.Message) error {
+	start := time.Now()
+	defer func() {
+		metrics.ForwardDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	topic := s.topicOf(message)
+	if matchesTopics(s.consumedTopics, topic) {
+		// a configuration error, the message would be consumed again
+		return s.failed(fmt.Errorf("the sink topic %s is consumed from the same cluster", topic), true)
+	}
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "publish "+topic,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingOperationTypePublish,
+			semconv.MessagingDestinationName(topic),
+			semconv.MessagingKafkaMessageKey(message.Key),
+		))
+	defer span.End()
+
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            []byte(message.Key),
+		Value:          message.Content,
+		Headers:        s.headers(ctx, message),
+		Timestamp:      message.Timestamp,
+	}
+	if err := produce(ctx, s.producer, msg); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return s.failed(err, false)
+	}
+	s.health.succeeded()
+	log.Ctx(ctx).Info().Msgf("message produced to topic %s", topic)
+	return nil
+}
+
+// topicOf returns the sink topic of a message.
+func (s *KafkaSink) topicOf(message domain.Message) string {
+	origin, _ := domain.ParseKey(message.Key)
+	return strings.NewReplacer(sinkTopicOrigin, origin, sinkTopicTopic, message.Topic).Replace(s.topic)
+}
+
+// headers returns the headers of the message with the trace context of the produce span,
+// so the consumers of the sink topic continue the trace.
+func (s *KafkaSink) headers(ctx context.Context, message domain.Message) []kafka.Header {
+	carrier := propagation.MapCarrier{}
+	for key, value := range message.Headers {
+		carrier[key] = value
+	}
+	if correlationID := correlationIDHeader(message, s.echoCorrelationID); correlationID != nil {
+		carrier[correlationID.Key] = string(correlationID.Value)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+
+	headers := make([]kafka.Header, 0, len(carrier))
+	for key, value := range carrier {
+		headers = append(headers, kafka.Header{Key: key, Value: []byte(value)})
+	}
+	return headers
+}
+
+// Live always returns nil, the failures of the destination are reported by Ready.
+func (s *KafkaSink) Live() error {
+	return nil
+}
+
+// Ready returns an error when the number of consecutive forward failures reaches the configured maximum,
+// the producer connects on the first message.
+func (s *KafkaSink) Ready() error {
+	return s.health.ready(s.broker)
+}
+
+// Close flushes pending messages and closes the Kafka producer.
+func (s *KafkaSink) Close() error {
+	if remaining := s.producer.Flush(flushTimeoutMs); remaining > 0 {
+		log.Warn().Msgf("%d messages were not produced to the sink before closing", remaining)
+	}
+	s.producer.Close()
+	return nil
+}
+
+// failed counts a message that could not be produced and returns the error describing the failure.
+// A client error is caused by the message, the cluster being up.
+func (s *KafkaSink) failed(err error, clientError bool) error {
+	class := errorClass(0, err)
+	if clientError {
+		class = "client_error"
+	}
+	metrics.ForwardFailures.WithLabelValues("0", class).Inc()
+	s.health.failed(err)
+	return &domain.ForwardError{ClientError: clientError, Attempts: 1, Err: err}
+}
@@ -0,0 +1,236 @@
+package repository
+
+import (
+	"anyker/config"
+	"anyker/internal/domain"
+	"anyker/internal/infrastructure/repository/mocks"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestNewKafkaSink(t *testing.T) {
+	t.Run("other cluster", func(t *testing.T) {
+		cfg := config.Config{
+			KafkaBroker:     "localhost:9092",
+			SinkKafkaBroker: "team-kafka:9092",
+			SinkKafkaTopic:  "{topic}",
+		}
+
+		sink, err := NewKafkaSink(cfg, "")
+		require.NoError(t, err)
+		assert.Equal(t, "{topic}", sink.(*KafkaSink).topic)
+		assert.Equal(t, "team-kafka:9092", sink.(*KafkaSink).broker)
+
+		// close without flushing, there is no broker to deliver to
+		sink.(*KafkaSink).producer.Close()
+	})
+
+	t.Run("same cluster with the topic of the route", func(t *testing.T) {
+		cfg := config.Config{KafkaBroker: "localhost:9092", KafkaTopic: "orders", SinkKafkaTopic: "{topic}"}
+
+		sink, err := NewKafkaSink(cfg, "bridge.telegram")
+		require.NoError(t, err)
+		assert.Equal(t, "bridge.telegram", sink.(*KafkaSink).topic)
+		assert.Equal(t, "localhost:9092", sink.(*KafkaSink).broker)
+		assert.Equal(t, []string{"orders"}, sink.(*KafkaSink).consumedTopics)
+
+		sink.(*KafkaSink).producer.Close()
+	})
+
+	t.Run("same cluster and consumed topic", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			topic string
+		}{
+			{name: "topic placeholder", topic: "{topic}"},
+			{name: "consumed topic", topic: "anyker-topic"},
+			{name: "topic matching a consumed pattern", topic: "events.telegram"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				cfg := config.Config{KafkaBroker: "localhost:9092", KafkaTopic: `anyker-topic,^events\..*`, SinkKafkaTopic: tt.topic}
+
+				_, err := NewKafkaSink(cfg, "")
+
+				assert.EqualError(t, err, "the sink topic "+tt.topic+" must differ from the consumed ones when producing to the source cluster")
+			})
+		}
+	})
+
+	t.Run("consumed topic of another source", func(t *testing.T) {
+		cfg := config.Config{SourceType: "nats", KafkaBroker: "localhost:9092", SinkKafkaTopic: "{topic}"}
+
+		sink, err := NewKafkaSink(cfg, "")
+		require.NoError(t, err)
+		assert.Nil(t, sink.(*KafkaSink).consumedTopics)
+
+		sink.(*KafkaSink).producer.Close()
+	})
+
+	t.Run("invalid property", func(t *testing.T) {
+		_, err := NewKafkaSink(config.Config{
+			SinkKafkaBroker:     "team-kafka:9092",
+			SinkKafkaProperties: map[string]string{"not.a.property": "true"},
+		}, "bridge")
+
+		assert.ErrorContains(t, err, "failed to create sink producer")
+	})
+}
+
+// sinkHeaders returns the headers of a produced message but the trace context, that depends on the global propagator.
+func sinkHeaders(msg *kafka.Message) map[string]string {
+	headers := make(map[string]string)
+	for _, h := range msg.Headers {
+		if h.Key != "traceparent" {
+			headers[h.Key] = string(h.Value)
+		}
+	}
+	return headers
+}
+
+func TestKafkaSink_Forward(t *testing.T) {
+	message := domain.Message{
+		Content:       []byte(`{"text":"hello"}`),
+		Key:           "telegram:123",
+		Headers:       map[string]string{"type": "text"},
+		CorrelationID: "generated",
+		Topic:         "orders",
+		Timestamp:     time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	t.Run("success", func(t *testing.T) {
+		mockKafkaProducer := new(mocks.KafkaProducer)
+		sink := newKafkaSink(config.Config{}, mockKafkaProducer, "bridge.{origin}.{topic}", "team-kafka:9092")
+		defer mockKafkaProducer.AssertExpectations(t)
+
+		var produced *kafka.Message
+		mockKafkaProducer.On("Produce", mock.AnythingOfType("*kafka.Message"), mock.Anything).
+			Run(func(args mock.Arguments) {
+				produced = args.Get(0).(*kafka.Message)
+				args.Get(1).(chan kafka.Event) <- produced
+			}).Return(nil).Once()
+
+		require.NoError(t, sink.Forward(context.Background(), message))
+
+		assert.Equal(t, "bridge.telegram.orders", *produced.TopicPartition.Topic)
+		assert.Equal(t, kafka.PartitionAny, produced.TopicPartition.Partition)
+		assert.Equal(t, "telegram:123", string(produced.Key))
+		assert.Equal(t, `{"text":"hello"}`, string(produced.Value))
+		assert.Equal(t, message.Timestamp, produced.Timestamp)
+		assert.Equal(t, map[string]string{"type": "text"}, sinkHeaders(produced))
+		assert.NoError(t, sink.Ready())
+	})
+
+	t.Run("correlation ID echo", func(t *testing.T) {
+		mockKafkaProducer := new(mocks.KafkaProducer)
+		sink := newKafkaSink(config.Config{CorrelationIDEcho: true}, mockKafkaProducer, "bridge", "team-kafka:9092")
+		defer mockKafkaProducer.AssertExpectations(t)
+
+		var produced *kafka.Message
+		mockKafkaProducer.On("Produce", mock.AnythingOfType("*kafka.Message"), mock.Anything).
+			Run(func(args mock.Arguments) {
+				produced = args.Get(0).(*kafka.Message)
+				args.Get(1).(chan kafka.Event) <- produced
+			}).Return(nil).Once()
+
+		require.NoError(t, sink.Forward(context.Background(), message))
+
+		assert.Equal(t, map[string]string{"type": "text", domain.CorrelationIDHeader: "generated"}, sinkHeaders(produced))
+	})
+
+	t.Run("consumed topic", func(t *testing.T) {
+		mockKafkaProducer := new(mocks.KafkaProducer)
+		sink := newKafkaSink(config.Config{}, mockKafkaProducer, "{origin}", "localhost:9092")
+		sink.consumedTopics = []string{"telegram"}
+		defer mockKafkaProducer.AssertExpectations(t)
+
+		err := sink.Forward(context.Background(), message)
+
+		var forwardErr *domain.ForwardError
+		require.ErrorAs(t, err, &forwardErr)
+		assert.True(t, forwardErr.ClientError)
+		assert.EqualError(t, err, "forward failed after 1 attempt(s): the sink topic telegram is consumed from the same cluster")
+	})
+
+	t.Run("delivery error", func(t *testing.T) {
+		mockKafkaProducer := new(mocks.KafkaProducer)
+		sink := newKafkaSink(config.Config{HealthMaxForwardFailures: 1}, mockKafkaProducer, "bridge", "team-kafka:9092")
+		defer mockKafkaProducer.AssertExpectations(t)
+
+		mockKafkaProducer.On("Produce", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				msg := args.Get(0).(*kafka.Message)
+				msg.TopicPartition.Error = kafka.NewError(kafka.ErrMsgTimedOut, "Local: Message timed out", false)
+				args.Get(1).(chan kafka.Event) <- msg
+			}).Return(nil).Once()
+
+		err := sink.Forward(context.Background(), message)
+
+		var forwardErr *domain.ForwardError
+		require.ErrorAs(t, err, &forwardErr)
+		assert.Equal(t, 1, forwardErr.Attempts)
+		assert.False(t, forwardErr.ClientError)
+		assert.ErrorContains(t, err, "failed to deliver message")
+		assert.EqualError(t, sink.Ready(), "1 consecutive failures forwarding to team-kafka:9092")
+		assert.NoError(t, sink.Live())
+	})
+}
+
+func TestKafkaSink_Forward_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	}()
+
+	mockKafkaProducer := new(mocks.KafkaProducer)
+	sink := newKafkaSink(config.Config{}, mockKafkaProducer, "bridge", "team-kafka:9092")
+	var produced *kafka.Message
+	mockKafkaProducer.On("Produce", mock.AnythingOfType("*kafka.Message"), mock.Anything).
+		Run(func(args mock.Arguments) {
+			produced = args.Get(0).(*kafka.Message)
+			args.Get(1).(chan kafka.Event) <- produced
+		}).Return(nil).Once()
+
+	// the traceparent of the consumed message is replaced by the one of the produce span
+	message := domain.Message{
+		Key:     "telegram:123",
+		Headers: map[string]string{"traceparent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"},
+	}
+	ctx := propagation.TraceContext{}.Extract(context.Background(), propagation.MapCarrier(message.Headers))
+	require.NoError(t, sink.Forward(ctx, message))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "publish bridge", span.Name())
+	assert.Equal(t, "b7ad6b7169203331", span.Parent().SpanID().String())
+	assert.Equal(t, []kafka.Header{{
+		Key:   "traceparent",
+		Value: []byte("00-0af7651916cd43dd8448eb211c80319c-" + span.SpanContext().SpanID().String() + "-01"),
+	}}, produced.Headers)
+}
+
+func TestKafkaSink_Close(t *testing.T) {
+	mockKafkaProducer := new(mocks.KafkaProducer)
+	sink := newKafkaSink(config.Config{}, mockKafkaProducer, "bridge", "team-kafka:9092")
+	defer mockKafkaProducer.AssertExpectations(t)
+
+	mockKafkaProducer.On("Flush", flushTimeoutMs).Return(0).Once()
+	mockKafkaProducer.On("Close").Return().Once()
+
+	assert.NoError(t, sink.Close())
+}
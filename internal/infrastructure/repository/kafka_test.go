@@ -4,14 +4,26 @@ import (
 	"anyker/config"
 	"anyker/internal/domain"
 	"anyker/internal/infrastructure/repository/mocks"
+	"anyker/internal/metrics"
+	"bytes"
 	"context"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestNewConsumer(t *testing.T) {
@@ -34,12 +46,95 @@ func TestNewConsumer(t *testing.T) {
 	// and assumes the underlying kafka library behaves as expected.
 }
 
+func TestNewConsumer_StartOffset(t *testing.T) {
+	cfg := config.Config{
+		KafkaBroker:      "localhost:9092",
+		KafkaGroupID:     "test-group",
+		KafkaTopic:       "test-topic",
+		KafkaStartOffset: "beginning",
+	}
+
+	t.Run("named offset", func(t *testing.T) {
+		consumer, err := NewConsumer(cfg)
+		assert.NoError(t, err)
+		assert.Equal(t, kafka.OffsetBeginning, *consumer.(*Consumer).startOffset)
+	})
+
+	t.Run("invalid offset", func(t *testing.T) {
+		invalidCfg := cfg
+		invalidCfg.KafkaStartOffset = "somewhere"
+
+		_, err := NewConsumer(invalidCfg)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid start offset")
+	})
+
+	t.Run("timestamp", func(t *testing.T) {
+		timestampCfg := cfg
+		timestampCfg.KafkaStartTimestamp = "2025-01-01T12:00:00Z"
+
+		consumer, err := NewConsumer(timestampCfg)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC), consumer.(*Consumer).startTimestamp)
+	})
+
+	t.Run("invalid timestamp", func(t *testing.T) {
+		invalidCfg := cfg
+		invalidCfg.KafkaStartTimestamp = "yesterday"
+
+		_, err := NewConsumer(invalidCfg)
+		assert.ErrorContains(t, err, "invalid start timestamp")
+	})
+}
+
+func TestNewConsumer_Topics(t *testing.T) {
+	cfg := config.Config{
+		KafkaBroker:  "localhost:9092",
+		KafkaGroupID: "test-group",
+		KafkaTopic:   "topic-a, ^events\\..*",
+	}
+
+	t.Run("topics and patterns", func(t *testing.T) {
+		consumer, err := NewConsumer(cfg)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"topic-a", "^events\\..*"}, consumer.(*Consumer).topics)
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		invalidCfg := cfg
+		invalidCfg.KafkaTopic = "^events("
+
+		_, err := NewConsumer(invalidCfg)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid topic pattern")
+	})
+}
+
+func TestParseTopics(t *testing.T) {
+	t.Run("single topic", func(t *testing.T) {
+		topics, err := parseTopics("test-topic")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"test-topic"}, topics)
+	})
+
+	t.Run("list with empty entries", func(t *testing.T) {
+		topics, err := parseTopics(" topic-a ,, topic-b,")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"topic-a", "topic-b"}, topics)
+	})
+
+	t.Run("no topics", func(t *testing.T) {
+		_, err := parseTopics(" , ")
+		assert.Error(t, err)
+	})
+}
+
 func TestConsumer_Consume(t *testing.T) {
 	t.Run("successful message consumption", func(t *testing.T) {
 		mockKafkaConsumer := new(mocks.KafkaConsumer)
 		consumer := &Consumer{
 			consumer: mockKafkaConsumer,
-			topic:    "test-topic",
+			topics:   []string{"test-topic"},
 		}
 		messagesChan := make(chan *domain.Message, 10)
 		ctx, cancel := context.WithCancel(context.Background())
@@ -47,13 +142,16 @@ func TestConsumer_Consume(t *testing.T) {
 		defer mockKafkaConsumer.AssertExpectations(t)
 		defer cancel()
 
-		mockKafkaConsumer.On("Subscribe", "test-topic", mock.Anything).Return(nil).Once()
+		mockKafkaConsumer.On("SubscribeTopics", []string{"test-topic"}, mock.Anything).Return(nil).Once()
 
 		// Simulate two messages, then a timeout, then context cancellation
+		topic := "test-topic"
 		msg1 := &kafka.Message{
-			Value:   []byte("message1"),
-			Headers: []kafka.Header{{Key: "correlation_id", Value: []byte("123")}},
-			Key:     []byte("key1"),
+			TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 2, Offset: 41},
+			Value:          []byte("message1"),
+			Headers:        []kafka.Header{{Key: "correlation_id", Value: []byte("123")}},
+			Key:            []byte("key1"),
+			Timestamp:      time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
 		}
 		msg2 := &kafka.Message{
 			Value:   []byte("message2"),
@@ -63,6 +161,9 @@ func TestConsumer_Consume(t *testing.T) {
 
 		mockKafkaConsumer.On("ReadMessage", mock.AnythingOfType("time.Duration")).Return(msg1, nil).Once()
 		mockKafkaConsumer.On("ReadMessage", mock.AnythingOfType("time.Duration")).Return(msg2, nil).Once()
+		mockKafkaConsumer.On("GetWatermarkOffsets", "test-topic", int32(2)).Return(int64(0), int64(50), nil).Once()
+		mockKafkaConsumer.On("GetWatermarkOffsets", "", int32(0)).Return(int64(0), int64(0), errors.New("unknown partition")).Once()
+		consumed := testutil.ToFloat64(metrics.MessagesConsumed.WithLabelValues("test-topic"))
 		mockKafkaConsumer.On("ReadMessage", mock.AnythingOfType("time.Duration")).Return(nil, kafka.NewError(kafka.ErrTimedOut, "Local: Timed out", false)).Once()
 		mockKafkaConsumer.On("ReadMessage", mock.AnythingOfType("time.Duration")).Return(nil, context.Canceled).Maybe() // For graceful exit
 
@@ -76,12 +177,19 @@ func TestConsumer_Consume(t *testing.T) {
 		assert.Equal(t, "message1", string(receivedMsg1.Content))
 		assert.Equal(t, "123", string(receivedMsg1.Headers["correlation_id"]))
 		assert.Equal(t, "key1", receivedMsg1.Key)
+		assert.Equal(t, "test-topic", receivedMsg1.Topic)
+		assert.Equal(t, int32(2), receivedMsg1.Partition)
+		assert.Equal(t, int64(41), receivedMsg1.Offset)
+		assert.Equal(t, time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC), receivedMsg1.Timestamp)
 
 		receivedMsg2 := <-messagesChan
 		assert.Equal(t, "message2", string(receivedMsg2.Content))
 		assert.Equal(t, "456", string(receivedMsg2.Headers["correlation_id"]))
 		assert.Equal(t, "key2", receivedMsg2.Key)
 
+		assert.Equal(t, consumed+1, testutil.ToFloat64(metrics.MessagesConsumed.WithLabelValues("test-topic")))
+		assert.Equal(t, float64(8), testutil.ToFloat64(metrics.ConsumerLag.WithLabelValues("test-topic", "2")))
+
 		// Give some time for the consumer to process timeout and context cancellation
 		time.Sleep(100 * time.Millisecond)
 		cancel()                           // Cancel the context to stop the consumer loop
@@ -92,7 +200,7 @@ func TestConsumer_Consume(t *testing.T) {
 		mockKafkaConsumer := new(mocks.KafkaConsumer)
 		consumer := &Consumer{
 			consumer: mockKafkaConsumer,
-			topic:    "test-topic",
+			topics:   []string{"test-topic"},
 		}
 		messagesChan := make(chan *domain.Message, 10)
 		ctx, cancel := context.WithCancel(context.Background())
@@ -101,7 +209,7 @@ func TestConsumer_Consume(t *testing.T) {
 		defer cancel()
 
 		expectedErr := errors.New("failed to subscribe")
-		mockKafkaConsumer.On("Subscribe", "test-topic", mock.Anything).Return(expectedErr).Once()
+		mockKafkaConsumer.On("SubscribeTopics", []string{"test-topic"}, mock.Anything).Return(expectedErr).Once()
 
 		err := consumer.Consume(ctx, messagesChan)
 		assert.Error(t, err)
@@ -112,7 +220,7 @@ func TestConsumer_Consume(t *testing.T) {
 		mockKafkaConsumer := new(mocks.KafkaConsumer)
 		consumer := &Consumer{
 			consumer: mockKafkaConsumer,
-			topic:    "test-topic",
+			topics:   []string{"test-topic"},
 		}
 		messagesChan := make(chan *domain.Message, 10)
 		ctx, cancel := context.WithCancel(context.Background())
@@ -120,7 +228,7 @@ func TestConsumer_Consume(t *testing.T) {
 		defer mockKafkaConsumer.AssertExpectations(t)
 		defer cancel()
 
-		mockKafkaConsumer.On("Subscribe", "test-topic", mock.Anything).Return(nil).Once()
+		mockKafkaConsumer.On("SubscribeTopics", []string{"test-topic"}, mock.Anything).Return(nil).Once()
 		expectedErr := errors.New("kafka read error")
 		mockKafkaConsumer.On("ReadMessage", mock.AnythingOfType("time.Duration")).Return(nil, expectedErr).Once()
 
@@ -134,7 +242,7 @@ func TestConsumer_Consume(t *testing.T) {
 		mockKafkaConsumer := new(mocks.KafkaConsumer)
 		consumer := &Consumer{
 			consumer: mockKafkaConsumer,
-			topic:    "test-topic",
+			topics:   []string{"test-topic"},
 		}
 		messagesChan := make(chan *domain.Message, 10)
 		ctx, cancelFunc := context.WithCancel(context.Background())
@@ -142,13 +250,14 @@ func TestConsumer_Consume(t *testing.T) {
 		defer mockKafkaConsumer.AssertExpectations(t)
 		defer cancelFunc()
 
-		mockKafkaConsumer.On("Subscribe", "test-topic", mock.Anything).Return(nil).Once()
+		mockKafkaConsumer.On("SubscribeTopics", []string{"test-topic"}, mock.Anything).Return(nil).Once()
 		msg := &kafka.Message{
 			Value: []byte("no_headers_message"),
 			Key:   []byte("key_no_headers"),
 		}
 		mockKafkaConsumer.On("ReadMessage", mock.AnythingOfType("time.Duration")).Return(msg, nil).Once()
 		mockKafkaConsumer.On("ReadMessage", mock.AnythingOfType("time.Duration")).Return(nil, context.Canceled).Maybe()
+		mockKafkaConsumer.On("GetWatermarkOffsets", "", int32(0)).Return(int64(0), int64(1), nil).Once()
 
 		go func() {
 			err := consumer.Consume(ctx, messagesChan)
@@ -166,6 +275,383 @@ func TestConsumer_Consume(t *testing.T) {
 	})
 }
 
+func TestTracePoll(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	}()
+
+	start := time.Now().Add(-time.Second)
+	tracePoll(context.Background(), &domain.Message{
+		Key:       "telegram:123",
+		Topic:     "orders",
+		Partition: 2,
+		Offset:    41,
+		Headers:   map[string]string{"traceparent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"},
+	}, start)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "poll orders", span.Name())
+	assert.Equal(t, trace.SpanKindConsumer, span.SpanKind())
+	assert.Equal(t, start, span.StartTime())
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", span.SpanContext().TraceID().String())
+	assert.Equal(t, "b7ad6b7169203331", span.Parent().SpanID().String())
+	assert.Contains(t, span.Attributes(), semconv.MessagingDestinationPartitionID("2"))
+	assert.Contains(t, span.Attributes(), semconv.MessagingKafkaMessageOffset(41))
+}
+
+func TestConsumer_Rebalance(t *testing.T) {
+	topic := "test-topic"
+	assigned := kafka.AssignedPartitions{Partitions: []kafka.TopicPartition{
+		{Topic: &topic, Partition: 0, Offset: kafka.OffsetInvalid},
+		{Topic: &topic, Partition: 1, Offset: kafka.OffsetInvalid},
+	}}
+	offsetsOf := func(partitions []kafka.TopicPartition) []kafka.Offset {
+		offsets := make([]kafka.Offset, len(partitions))
+		for i, tp := range partitions {
+			offsets[i] = tp.Offset
+		}
+		return offsets
+	}
+
+	t.Run("no replay configured", func(t *testing.T) {
+		mockKafkaConsumer := new(mocks.KafkaConsumer)
+		consumer := &Consumer{consumer: mockKafkaConsumer, topics: []string{topic}}
+		defer mockKafkaConsumer.AssertExpectations(t)
+
+		err := consumer.rebalance(nil, assigned)
+		assert.NoError(t, err)
+	})
+
+	t.Run("start offset", func(t *testing.T) {
+		mockKafkaConsumer := new(mocks.KafkaConsumer)
+		startOffset := kafka.Offset(100)
+		consumer := &Consumer{consumer: mockKafkaConsumer, topics: []string{topic}, startOffset: &startOffset}
+		defer mockKafkaConsumer.AssertExpectations(t)
+
+		mockKafkaConsumer.On("GetRebalanceProtocol").Return("EAGER").Once()
+		mockKafkaConsumer.On("Assign", mock.MatchedBy(func(partitions []kafka.TopicPartition) bool {
+			return assert.ObjectsAreEqual([]kafka.Offset{100, 100}, offsetsOf(partitions))
+		})).Return(nil).Once()
+
+		err := consumer.rebalance(nil, assigned)
+		assert.NoError(t, err)
+
+		// partitions are only replayed once, later assignments resume from the committed offsets
+		err = consumer.rebalance(nil, assigned)
+		assert.NoError(t, err)
+	})
+
+	t.Run("start timestamp", func(t *testing.T) {
+		mockKafkaConsumer := new(mocks.KafkaConsumer)
+		startTimestamp := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+		consumer := &Consumer{consumer: mockKafkaConsumer, topics: []string{topic}, startTimestamp: startTimestamp}
+		defer mockKafkaConsumer.AssertExpectations(t)
+
+		mockKafkaConsumer.On("OffsetsForTimes", mock.MatchedBy(func(times []kafka.TopicPartition) bool {
+			ts := kafka.Offset(startTimestamp.UnixMilli())
+			return assert.ObjectsAreEqual([]kafka.Offset{ts, ts}, offsetsOf(times))
+		}), offsetsForTimesTimeoutMs).Return([]kafka.TopicPartition{
+			{Topic: &topic, Partition: 0, Offset: 10},
+			{Topic: &topic, Partition: 1, Offset: 20},
+		}, nil).Once()
+		mockKafkaConsumer.On("GetRebalanceProtocol").Return("COOPERATIVE").Once()
+		mockKafkaConsumer.On("IncrementalAssign", mock.MatchedBy(func(partitions []kafka.TopicPartition) bool {
+			return assert.ObjectsAreEqual([]kafka.Offset{10, 20}, offsetsOf(partitions))
+		})).Return(nil).Once()
+
+		err := consumer.rebalance(nil, assigned)
+		assert.NoError(t, err)
+	})
+
+	t.Run("start timestamp lookup error", func(t *testing.T) {
+		mockKafkaConsumer := new(mocks.KafkaConsumer)
+		consumer := &Consumer{consumer: mockKafkaConsumer, topics: []string{topic}, startTimestamp: time.Now()}
+		defer mockKafkaConsumer.AssertExpectations(t)
+
+		mockKafkaConsumer.On("OffsetsForTimes", mock.Anything, offsetsForTimesTimeoutMs).
+			Return(nil, errors.New("broker not available")).Once()
+
+		err := consumer.rebalance(nil, assigned)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to get offsets for start timestamp")
+	})
+
+	t.Run("revoked partitions are left to the default handling", func(t *testing.T) {
+		mockKafkaConsumer := new(mocks.KafkaConsumer)
+		startOffset := kafka.Offset(100)
+		consumer := &Consumer{consumer: mockKafkaConsumer, topics: []string{topic}, startOffset: &startOffset}
+		defer mockKafkaConsumer.AssertExpectations(t)
+
+		err := consumer.rebalance(nil, kafka.RevokedPartitions{Partitions: assigned.Partitions})
+		assert.NoError(t, err)
+	})
+}
+
+func TestConsumer_Health(t *testing.T) {
+	topic := "test-topic"
+	partitions := []kafka.TopicPartition{{Topic: &topic, Partition: 0}, {Topic: &topic, Partition: 1}}
+	consumer := &Consumer{stallTimeout: time.Minute}
+
+	t.Run("not started", func(t *testing.T) {
+		assert.NoError(t, consumer.Live())
+		assert.EqualError(t, consumer.Ready(), "kafka consumer is not subscribed")
+
+		consumer.subscribed.Store(true)
+		assert.EqualError(t, consumer.Ready(), "kafka consumer has not polled yet")
+	})
+
+	t.Run("assignment", func(t *testing.T) {
+		consumer.lastPoll.Store(time.Now().UnixNano())
+		assert.EqualError(t, consumer.Ready(), "kafka consumer has no partition assigned")
+
+		assert.NoError(t, consumer.rebalance(nil, kafka.AssignedPartitions{Partitions: partitions}))
+		assert.NoError(t, consumer.Ready())
+		assert.NoError(t, consumer.Live())
+
+		assert.NoError(t, consumer.rebalance(nil, kafka.RevokedPartitions{Partitions: partitions[:1]}))
+		assert.NoError(t, consumer.Ready())
+
+		assert.NoError(t, consumer.rebalance(nil, kafka.RevokedPartitions{Partitions: partitions[1:]}))
+		assert.EqualError(t, consumer.Ready(), "kafka consumer has no partition assigned")
+	})
+
+	t.Run("stalled", func(t *testing.T) {
+		consumer.lastPoll.Store(time.Now().Add(-2 * time.Minute).UnixNano())
+
+		err := consumer.Live()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "kafka consumer has not polled for 2m")
+	})
+
+	t.Run("uncommitted messages", func(t *testing.T) {
+		consumer := &Consumer{stallTimeout: time.Minute, maxPendingOffsets: 2}
+		consumer.lastPoll.Store(time.Now().UnixNano())
+		key := partitionKey{topic: topic, partition: 0}
+		consumer.tracker.track(key, 10)
+		assert.NoError(t, consumer.Live())
+
+		consumer.tracker.track(key, 11)
+		assert.EqualError(t, consumer.Live(), "kafka consumer has 2 uncommitted messages in test-topic[0] from offset 10")
+	})
+
+	t.Run("uncommitted since a message failed", func(t *testing.T) {
+		consumer := &Consumer{stallTimeout: time.Minute, maxUncommittedAge: time.Minute}
+		consumer.lastPoll.Store(time.Now().UnixNano())
+		key := partitionKey{topic: topic, partition: 0}
+		consumer.tracker.track(key, 10)
+		consumer.tracker.track(key, 11)
+
+		assert.NoError(t, consumer.Reject(context.Background(), domain.Message{Topic: topic, Partition: 0, Offset: 10}))
+		assert.NoError(t, consumer.Commit(context.Background(), domain.Message{Topic: topic, Partition: 0, Offset: 11}))
+		assert.NoError(t, consumer.Live())
+
+		consumer.tracker.partitions[key].failedAt = time.Now().Add(-2 * time.Minute)
+		assert.EqualError(t, consumer.Live(), "kafka consumer has 2 uncommitted messages in test-topic[0] from offset 10, offset 10 failed 2m0s ago")
+	})
+}
+
+func TestConsumer_Pause(t *testing.T) {
+	topic := "test-topic"
+	mockKafkaConsumer := new(mocks.KafkaConsumer)
+	consumer := &Consumer{consumer: mockKafkaConsumer, topics: []string{topic}, stallTimeout: time.Minute}
+	defer mockKafkaConsumer.AssertExpectations(t)
+	partitionCount := func(n int) interface{} {
+		return mock.MatchedBy(func(partitions []kafka.TopicPartition) bool { return len(partitions) == n })
+	}
+
+	assert.NoError(t, consumer.rebalance(nil, kafka.AssignedPartitions{Partitions: []kafka.TopicPartition{{Topic: &topic, Partition: 0}}}))
+
+	// nothing to do until paused
+	consumer.applyPause()
+
+	mockKafkaConsumer.On("Pause", partitionCount(1)).Return(nil).Once()
+	consumer.Pause()
+	consumer.applyPause()
+	consumer.applyPause()
+
+	// partitions assigned while paused are paused too
+	mockKafkaConsumer.On("Pause", partitionCount(2)).Return(nil).Once()
+	assert.NoError(t, consumer.rebalance(nil, kafka.AssignedPartitions{Partitions: []kafka.TopicPartition{{Topic: &topic, Partition: 1}}}))
+	consumer.applyPause()
+
+	// stalled while paused is still live
+	consumer.lastPoll.Store(time.Now().Add(-2 * time.Minute).UnixNano())
+	assert.NoError(t, consumer.Live())
+
+	mockKafkaConsumer.On("Resume", partitionCount(2)).Return(errors.New("broker down")).Once()
+	mockKafkaConsumer.On("Resume", partitionCount(2)).Return(nil).Once()
+	consumer.Resume()
+	consumer.applyPause() // failed, retried on the next poll
+	consumer.applyPause()
+	consumer.applyPause()
+
+	assert.Error(t, consumer.Live())
+}
+
+func TestConsumer_PauseWaitingForWorker(t *testing.T) {
+	topic := "test-topic"
+	mockKafkaConsumer := new(mocks.KafkaConsumer)
+	consumer := &Consumer{
+		consumer:       mockKafkaConsumer,
+		topics:         []string{topic},
+		pauseRequested: make(chan struct{}, 1),
+	}
+	defer mockKafkaConsumer.AssertExpectations(t)
+	assert.NoError(t, consumer.rebalance(nil, kafka.AssignedPartitions{Partitions: []kafka.TopicPartition{{Topic: &topic, Partition: 0}}}))
+
+	msg := &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 0, Offset: 1}}
+	timedOut := kafka.NewError(kafka.ErrTimedOut, "Local: Timed out", false)
+	polled := make(chan struct{})
+	paused := make(chan struct{})
+	polledWhilePaused := make(chan struct{}, 10)
+	mockKafkaConsumer.On("SubscribeTopics", []string{topic}, mock.Anything).Return(nil).Once()
+	mockKafkaConsumer.On("ReadMessage", time.Second).Return(msg, nil).Run(func(mock.Arguments) { close(polled) }).Once()
+	mockKafkaConsumer.On("GetWatermarkOffsets", topic, int32(0)).Return(int64(0), int64(2), nil).Once()
+	mockKafkaConsumer.On("Pause", mock.Anything).Return(nil).Run(func(mock.Arguments) { close(paused) }).Once()
+	mockKafkaConsumer.On("ReadMessage", time.Duration(0)).Return(nil, timedOut).
+		Run(func(mock.Arguments) { polledWhilePaused <- struct{}{} })
+
+	// no worker receives the message, the consume loop waits to send it
+	ctx, cancel := context.WithCancel(context.Background())
+	messages := make(chan *domain.Message)
+	done := make(chan error)
+	go func() { done <- consumer.Consume(ctx, messages) }()
+	<-polled
+
+	// the pause is applied while waiting and Kafka is still polled to stay in the group
+	consumer.Pause()
+	select {
+	case <-paused:
+	case <-time.After(time.Second):
+		t.Fatal("partitions not paused while waiting for a worker")
+	}
+	select {
+	case <-polledWhilePaused:
+	case <-time.After(3 * pausedPollInterval):
+		t.Fatal("Kafka not polled while paused")
+	}
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestConsumer_Commit(t *testing.T) {
+	msg := domain.Message{Topic: "test-topic", Partition: 2, Offset: 41}
+
+	key := partitionKey{topic: "test-topic", partition: 2}
+
+	t.Run("commits the next offset", func(t *testing.T) {
+		mockKafkaConsumer := new(mocks.KafkaConsumer)
+		consumer := &Consumer{consumer: mockKafkaConsumer}
+		consumer.tracker.track(key, 41)
+		defer mockKafkaConsumer.AssertExpectations(t)
+
+		mockKafkaConsumer.On("CommitOffsets", mock.MatchedBy(func(offsets []kafka.TopicPartition) bool {
+			return len(offsets) == 1 &&
+				*offsets[0].Topic == "test-topic" &&
+				offsets[0].Partition == 2 &&
+				offsets[0].Offset == 42
+		})).Return(nil, nil).Once()
+
+		err := consumer.Commit(context.Background(), msg)
+		assert.NoError(t, err)
+	})
+
+	t.Run("waits for previous messages in flight", func(t *testing.T) {
+		mockKafkaConsumer := new(mocks.KafkaConsumer)
+		consumer := &Consumer{consumer: mockKafkaConsumer}
+		consumer.tracker.track(key, 40)
+		consumer.tracker.track(key, 41)
+		defer mockKafkaConsumer.AssertExpectations(t)
+
+		err := consumer.Commit(context.Background(), msg)
+		assert.NoError(t, err)
+		mockKafkaConsumer.AssertNotCalled(t, "CommitOffsets", mock.Anything)
+
+		mockKafkaConsumer.On("CommitOffsets", mock.MatchedBy(func(offsets []kafka.TopicPartition) bool {
+			return len(offsets) == 1 && offsets[0].Offset == 42
+		})).Return(nil, nil).Once()
+
+		err = consumer.Commit(context.Background(), domain.Message{Topic: "test-topic", Partition: 2, Offset: 40})
+		assert.NoError(t, err)
+	})
+
+	t.Run("revoked partition is not committed", func(t *testing.T) {
+		mockKafkaConsumer := new(mocks.KafkaConsumer)
+		consumer := &Consumer{consumer: mockKafkaConsumer}
+		consumer.tracker.track(key, 41)
+		defer mockKafkaConsumer.AssertExpectations(t)
+
+		topic := "test-topic"
+		err := consumer.rebalance(nil, kafka.RevokedPartitions{Partitions: []kafka.TopicPartition{{Topic: &topic, Partition: 2}}})
+		assert.NoError(t, err)
+
+		err = consumer.Commit(context.Background(), msg)
+		assert.NoError(t, err)
+		mockKafkaConsumer.AssertNotCalled(t, "CommitOffsets", mock.Anything)
+	})
+
+	t.Run("commits are serialized", func(t *testing.T) {
+		mockKafkaConsumer := new(mocks.KafkaConsumer)
+		consumer := &Consumer{consumer: mockKafkaConsumer}
+		consumer.tracker.track(key, 40)
+		consumer.tracker.track(key, 41)
+		defer mockKafkaConsumer.AssertExpectations(t)
+
+		var committed []kafka.Offset
+		var mu sync.Mutex
+		inCommit, release := make(chan struct{}), make(chan struct{})
+		mockKafkaConsumer.On("CommitOffsets", mock.Anything).Run(func(args mock.Arguments) {
+			offset := args.Get(0).([]kafka.TopicPartition)[0].Offset
+			if offset == 41 {
+				close(inCommit)
+				<-release
+			}
+			mu.Lock()
+			committed = append(committed, offset)
+			mu.Unlock()
+		}).Return(nil, nil).Twice()
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, consumer.Commit(context.Background(), domain.Message{Topic: "test-topic", Partition: 2, Offset: 40}))
+		}()
+		<-inCommit
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, consumer.Commit(context.Background(), msg))
+		}()
+		// the commit of 42 waits for the one of 41
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		assert.Equal(t, []kafka.Offset{41, 42}, committed)
+	})
+
+	t.Run("commit error", func(t *testing.T) {
+		mockKafkaConsumer := new(mocks.KafkaConsumer)
+		consumer := &Consumer{consumer: mockKafkaConsumer}
+		consumer.tracker.track(key, 41)
+		defer mockKafkaConsumer.AssertExpectations(t)
+
+		mockKafkaConsumer.On("CommitOffsets", mock.Anything).Return(nil, errors.New("coordinator not available")).Once()
+
+		err := consumer.Commit(context.Background(), msg)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to commit offset")
+	})
+}
+
 func TestConsumer_Close(t *testing.T) {
 	mockKafkaConsumer := new(mocks.KafkaConsumer)
 	consumer := &Consumer{
@@ -192,3 +678,19 @@ func TestConsumer_Close(t *testing.T) {
 		assert.Equal(t, expectedErr, err)
 	})
 }
+
+func TestLogProducerEvents(t *testing.T) {
+	var buf bytes.Buffer
+	previous := log.Logger
+	log.Logger = zerolog.New(&buf).Level(zerolog.InfoLevel)
+	defer func() { log.Logger = previous }()
+
+	// only the errors are logged above debug
+	events := make(chan kafka.Event, 2)
+	events <- kafka.NewError(kafka.ErrAllBrokersDown, "1/1 brokers are down", false)
+	events <- kafka.OAuthBearerTokenRefresh{}
+	close(events)
+	logProducerEvents("dead-letter", events)
+
+	assert.JSONEq(t, `{"level":"error","error":"1/1 brokers are down","message":"dead-letter producer error"}`, buf.String())
+}
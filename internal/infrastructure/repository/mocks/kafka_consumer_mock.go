@@ -14,6 +14,24 @@ type KafkaConsumer struct {
 	mock.Mock
 }
 
+// Assign provides a mock function with given fields: partitions
+func (_m *KafkaConsumer) Assign(partitions []kafka.TopicPartition) error {
+	ret := _m.Called(partitions)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Assign")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]kafka.TopicPartition) error); ok {
+		r0 = rf(partitions)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Close provides a mock function with no fields
 func (_m *KafkaConsumer) Close() error {
 	ret := _m.Called()
@@ -32,6 +50,155 @@ func (_m *KafkaConsumer) Close() error {
 	return r0
 }
 
+// CommitOffsets provides a mock function with given fields: offsets
+func (_m *KafkaConsumer) CommitOffsets(offsets []kafka.TopicPartition) ([]kafka.TopicPartition, error) {
+	ret := _m.Called(offsets)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CommitOffsets")
+	}
+
+	var r0 []kafka.TopicPartition
+	var r1 error
+	if rf, ok := ret.Get(0).(func([]kafka.TopicPartition) ([]kafka.TopicPartition, error)); ok {
+		return rf(offsets)
+	}
+	if rf, ok := ret.Get(0).(func([]kafka.TopicPartition) []kafka.TopicPartition); ok {
+		r0 = rf(offsets)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]kafka.TopicPartition)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func([]kafka.TopicPartition) error); ok {
+		r1 = rf(offsets)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRebalanceProtocol provides a mock function with no fields
+func (_m *KafkaConsumer) GetRebalanceProtocol() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRebalanceProtocol")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// GetWatermarkOffsets provides a mock function with given fields: topic, partition
+func (_m *KafkaConsumer) GetWatermarkOffsets(topic string, partition int32) (int64, int64, error) {
+	ret := _m.Called(topic, partition)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetWatermarkOffsets")
+	}
+
+	var r0 int64
+	var r1 int64
+	var r2 error
+	if rf, ok := ret.Get(0).(func(string, int32) (int64, int64, error)); ok {
+		return rf(topic, partition)
+	}
+	if rf, ok := ret.Get(0).(func(string, int32) int64); ok {
+		r0 = rf(topic, partition)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, int32) int64); ok {
+		r1 = rf(topic, partition)
+	} else {
+		r1 = ret.Get(1).(int64)
+	}
+
+	if rf, ok := ret.Get(2).(func(string, int32) error); ok {
+		r2 = rf(topic, partition)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// IncrementalAssign provides a mock function with given fields: partitions
+func (_m *KafkaConsumer) IncrementalAssign(partitions []kafka.TopicPartition) error {
+	ret := _m.Called(partitions)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IncrementalAssign")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]kafka.TopicPartition) error); ok {
+		r0 = rf(partitions)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// OffsetsForTimes provides a mock function with given fields: times, timeoutMs
+func (_m *KafkaConsumer) OffsetsForTimes(times []kafka.TopicPartition, timeoutMs int) ([]kafka.TopicPartition, error) {
+	ret := _m.Called(times, timeoutMs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for OffsetsForTimes")
+	}
+
+	var r0 []kafka.TopicPartition
+	var r1 error
+	if rf, ok := ret.Get(0).(func([]kafka.TopicPartition, int) ([]kafka.TopicPartition, error)); ok {
+		return rf(times, timeoutMs)
+	}
+	if rf, ok := ret.Get(0).(func([]kafka.TopicPartition, int) []kafka.TopicPartition); ok {
+		r0 = rf(times, timeoutMs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]kafka.TopicPartition)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func([]kafka.TopicPartition, int) error); ok {
+		r1 = rf(times, timeoutMs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Pause provides a mock function with given fields: partitions
+func (_m *KafkaConsumer) Pause(partitions []kafka.TopicPartition) error {
+	ret := _m.Called(partitions)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Pause")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]kafka.TopicPartition) error); ok {
+		r0 = rf(partitions)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // ReadMessage provides a mock function with given fields: timeout
 func (_m *KafkaConsumer) ReadMessage(timeout time.Duration) (*kafka.Message, error) {
 	ret := _m.Called(timeout)
@@ -62,17 +229,35 @@ func (_m *KafkaConsumer) ReadMessage(timeout time.Duration) (*kafka.Message, err
 	return r0, r1
 }
 
-// Subscribe provides a mock function with given fields: topic, rebalanceCb
-func (_m *KafkaConsumer) Subscribe(topic string, rebalanceCb kafka.RebalanceCb) error {
-	ret := _m.Called(topic, rebalanceCb)
+// Resume provides a mock function with given fields: partitions
+func (_m *KafkaConsumer) Resume(partitions []kafka.TopicPartition) error {
+	ret := _m.Called(partitions)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Resume")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]kafka.TopicPartition) error); ok {
+		r0 = rf(partitions)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SubscribeTopics provides a mock function with given fields: topics, rebalanceCb
+func (_m *KafkaConsumer) SubscribeTopics(topics []string, rebalanceCb kafka.RebalanceCb) error {
+	ret := _m.Called(topics, rebalanceCb)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Subscribe")
+		panic("no return value specified for SubscribeTopics")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string, kafka.RebalanceCb) error); ok {
-		r0 = rf(topic, rebalanceCb)
+	if rf, ok := ret.Get(0).(func([]string, kafka.RebalanceCb) error); ok {
+		r0 = rf(topics, rebalanceCb)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -0,0 +1,267 @@
+package repository
+
+import (
+	"anyker/config"
+	"anyker/internal/domain"
+	"anyker/internal/metrics"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/rs/zerolog/log"
+)
+
+// natsSetupTimeout bounds the creation of the stream and of the consumer.
+const natsSetupTimeout = 10 * time.Second
+
+// natsDefaultAckWait is the ack wait of the server when none is configured.
+const natsDefaultAckWait = 30 * time.Second
+
+// NATSConsumer implements domain.ConsumerRepository for NATS JetStream, with a durable pull consumer
+// shared by all the instances. Messages are acked once forwarded and nacked when they could not be,
+// JetStream delivering them again until they have been delivered maxDeliver times.
+// The messages sent to the workers are kept in progress until acked or nacked, so they are not delivered
+// again while waiting in the worker queues or being forwarded.
+type NATSConsumer struct {
+	conn       *nats.Conn
+	consumer   jetstream.Consumer
+	stream     string
+	maxDeliver int
+	ackWait    time.Duration
+	// pullMaxMessages bounds the messages buffered by the client, which are not in progress yet
+	pullMaxMessages int
+	consuming       atomic.Bool
+
+	// inflight holds the messages sent to the workers and not acked or nacked yet, by stream sequence.
+	inflight sync.Map
+}
+
+// NewNATSConsumer connects to the NATS server and creates or updates the durable consumer of the stream,
+// creating the stream if it does not exist. Without subjects, every subject of the stream is consumed,
+// and a missing stream is created with the <stream>.> subject.
+func NewNATSConsumer(config config.Config) (domain.ConsumerRepository, error) {
+	conn, err := nats.Connect(config.NATSURL, nats.Name(config.NanobotName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS server: %w", err)
+	}
+	consumer, err := newNATSConsumer(conn, config)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return consumer, nil
+}
+
+func newNATSConsumer(conn *nats.Conn, config config.Config) (*NATSConsumer, error) {
+	js, err := jetstream.New(conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), natsSetupTimeout)
+	defer cancel()
+
+	stream, err := js.Stream(ctx, config.NATSStream)
+	if errors.Is(err, jetstream.ErrStreamNotFound) {
+		subjects := config.NATSSubjects
+		if len(subjects) == 0 {
+			subjects = []string{config.NATSStream + ".>"}
+		}
+		log.Info().Msgf("creating NATS stream %s with subjects %v", config.NATSStream, subjects)
+		stream, err = js.CreateStream(ctx, jetstream.StreamConfig{Name: config.NATSStream, Subjects: subjects})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stream %s: %w", config.NATSStream, err)
+	}
+
+	consumerConfig := jetstream.ConsumerConfig{
+		Durable:    config.NATSDurable,
+		AckPolicy:  jetstream.AckExplicitPolicy,
+		AckWait:    config.NATSAckWait,
+		MaxDeliver: config.NATSMaxDeliver,
+	}
+	if len(config.NATSSubjects) == 1 {
+		// a single filter is supported by the servers older than 2.10 too
+		consumerConfig.FilterSubject = config.NATSSubjects[0]
+	} else {
+		consumerConfig.FilterSubjects = config.NATSSubjects
+	}
+	consumer, err := stream.CreateOrUpdateConsumer(ctx, consumerConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consumer %s: %w", config.NATSDurable, err)
+	}
+
+	ackWait := config.NATSAckWait
+	if ackWait <= 0 {
+		ackWait = natsDefaultAckWait
+	}
+	return &NATSConsumer{
+		conn:            conn,
+		consumer:        consumer,
+		stream:          config.NATSStream,
+		maxDeliver:      config.NATSMaxDeliver,
+		ackWait:         ackWait,
+		pullMaxMessages: max(config.WorkerCount, 1),
+	}, nil
+}
+
+// Consume pulls the messages of the consumer and sends them to the provided channel
+// until the context is cancelled or the consumer can't pull anymore.
+// A message delivered again while still in flight, e.g. after a network partition, is not sent twice.
+func (c *NATSConsumer) Consume(ctx context.Context, messages chan<- *domain.Message) error {
+	defer close(messages)
+
+	iter, err := c.consumer.Messages(jetstream.PullMaxMessages(c.pullMaxMessages))
+	if err != nil {
+		return fmt.Errorf("failed to consume stream %s: %w", c.stream, err)
+	}
+	defer iter.Stop()
+	stop := context.AfterFunc(ctx, iter.Stop)
+	defer stop()
+	c.consuming.Store(true)
+	defer c.consuming.Store(false)
+	heartbeatCtx, stopHeartbeat := context.WithCancel(context.Background())
+	defer stopHeartbeat()
+	go c.heartbeat(heartbeatCtx)
+
+	for {
+		msg, err := iter.Next()
+		if err != nil {
+			if errors.Is(err, jetstream.ErrMsgIteratorClosed) {
+				return nil // Graceful exit on context cancellation
+			}
+			return fmt.Errorf("failed to read message: %w", err)
+		}
+		message, err := natsMessage(c.stream, msg)
+		if err != nil {
+			return err
+		}
+		log.Debug().Msgf("headers received from NATS message %v", message.Headers)
+		log.Debug().Msgf("subject receive from NATS message %v", msg.Subject())
+		log.Debug().Msgf("payload receive from NATS message %v", string(message.Content))
+
+		if _, loaded := c.inflight.LoadOrStore(message.Offset, msg); loaded {
+			// the first delivery is still being processed and will be acked or nacked
+			log.Warn().Msgf("NATS message %d delivered again while in flight, skipped", message.Offset)
+			continue
+		}
+		metrics.MessagesConsumed.WithLabelValues(message.Topic).Inc()
+		messages <- message
+	}
+}
+
+// heartbeat tells the server that the messages in flight are still being processed every half ack wait,
+// so they are not delivered again, until the context is cancelled.
+func (c *NATSConsumer) heartbeat(ctx context.Context) {
+	ticker := time.NewTicker(c.ackWait / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.inflight.Range(func(_, msg any) bool {
+				if err := msg.(jetstream.Msg).InProgress(); err != nil {
+					log.Warn().Err(err).Msg("failed to mark NATS message in progress")
+				}
+				return true
+			})
+		}
+	}
+}
+
+// natsMessage converts a JetStream message of the stream to a message. The last token of the subject is the message key,
+// so messages are published to subjects such as anyker.telegram.telegram:123 for the key telegram:123,
+// and the stream sequence is the offset, to ack the message once processed. The stream is the topic, not the subject,
+// as the topic labels the metrics and names the spans and the sink topics, which must not have a value per key.
+func natsMessage(stream string, msg jetstream.Msg) (*domain.Message, error) {
+	metadata, err := msg.Metadata()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message metadata: %w", err)
+	}
+	headers := make(map[string]string, len(msg.Headers()))
+	for key := range msg.Headers() {
+		headers[key] = msg.Headers().Get(key)
+	}
+	subject := msg.Subject()
+
+	return &domain.Message{
+		Content:   msg.Data(),
+		Headers:   headers,
+		Key:       subject[strings.LastIndexByte(subject, '.')+1:],
+		Topic:     stream,
+		Offset:    int64(metadata.Sequence.Stream),
+		Timestamp: metadata.Timestamp,
+	}, nil
+}
+
+// Commit acks a message, so it is not delivered again.
+func (c *NATSConsumer) Commit(_ context.Context, message domain.Message) error {
+	msg, err := c.take(message)
+	if err != nil {
+		return err
+	}
+	if err := msg.Ack(); err != nil {
+		return fmt.Errorf("failed to ack message: %w", err)
+	}
+	return nil
+}
+
+// Reject nacks a message that could not be forwarded, so JetStream delivers it again right away.
+// A message already delivered maxDeliver times is terminated instead, it would not be delivered again anyway.
+func (c *NATSConsumer) Reject(ctx context.Context, message domain.Message) error {
+	msg, err := c.take(message)
+	if err != nil {
+		return err
+	}
+	if metadata, err := msg.Metadata(); err == nil && c.maxDeliver > 0 && metadata.NumDelivered >= uint64(c.maxDeliver) {
+		log.Ctx(ctx).Warn().Msgf("message delivered %d times, it will not be delivered again", metadata.NumDelivered)
+		if err := msg.Term(); err != nil {
+			return fmt.Errorf("failed to terminate message: %w", err)
+		}
+		return nil
+	}
+	if err := msg.Nak(); err != nil {
+		return fmt.Errorf("failed to nack message: %w", err)
+	}
+	return nil
+}
+
+// take removes a message from the in-flight ones.
+func (c *NATSConsumer) take(message domain.Message) (jetstream.Msg, error) {
+	msg, ok := c.inflight.LoadAndDelete(message.Offset)
+	if !ok {
+		return nil, fmt.Errorf("message %d is not in flight", message.Offset)
+	}
+	return msg.(jetstream.Msg), nil
+}
+
+// Live fails when the connection to the server is closed. A disconnected client reconnects by itself.
+func (c *NATSConsumer) Live() error {
+	if c.conn.IsClosed() {
+		return errors.New("NATS connection is closed")
+	}
+	return nil
+}
+
+// Ready fails until the consumer is pulling messages, or while disconnected from the server.
+func (c *NATSConsumer) Ready() error {
+	if !c.consuming.Load() {
+		return errors.New("NATS consumer is not consuming")
+	}
+	if !c.conn.IsConnected() {
+		return errors.New("NATS connection is not connected")
+	}
+	return nil
+}
+
+// Close closes the connection, the messages not acked yet are delivered again once their ack wait has elapsed.
+func (c *NATSConsumer) Close() error {
+	c.conn.Close()
+	return nil
+}
@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"anyker/config"
+	"anyker/internal/domain"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runNATSServer starts an embedded NATS server with JetStream, stopped at the end of the test.
+func runNATSServer(t *testing.T) *server.Server {
+	t.Helper()
+	s, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      server.RANDOM_PORT,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	require.NoError(t, err)
+	go s.Start()
+	require.True(t, s.ReadyForConnections(5*time.Second), "NATS server not ready")
+	t.Cleanup(s.Shutdown)
+	return s
+}
+
+// natsPublisher connects a JetStream client to the server, to publish the test messages.
+func natsPublisher(t *testing.T, s *server.Server) jetstream.JetStream {
+	t.Helper()
+	conn, err := nats.Connect(s.ClientURL())
+	require.NoError(t, err)
+	t.Cleanup(conn.Close)
+	js, err := jetstream.New(conn)
+	require.NoError(t, err)
+	return js
+}
+
+// startNATSConsumer creates a consumer and consumes in the background until the end of the test.
+func startNATSConsumer(t *testing.T, cfg config.Config) (*NATSConsumer, <-chan *domain.Message) {
+	t.Helper()
+	consumer, err := NewNATSConsumer(cfg)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	messages := make(chan *domain.Message)
+	done := make(chan error)
+	go func() { done <- consumer.Consume(ctx, messages) }()
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-done)
+		consumer.Close()
+	})
+	return consumer.(*NATSConsumer), messages
+}
+
+func natsConfig(s *server.Server) config.Config {
+	return config.Config{
+		NATSURL:        s.ClientURL(),
+		NATSStream:     "anyker",
+		NATSDurable:    "anyker",
+		NATSAckWait:    30 * time.Second,
+		NATSMaxDeliver: 2,
+		NanobotName:    "anyker-nanobot-1",
+	}
+}
+
+// noMessage asserts that no message is consumed for a while.
+func noMessage(t *testing.T, messages <-chan *domain.Message) {
+	t.Helper()
+	select {
+	case message := <-messages:
+		t.Fatalf("unexpected message %s", message.Topic)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestNATSConsumer_Consume(t *testing.T) {
+	s := runNATSServer(t)
+	consumer, messages := startNATSConsumer(t, natsConfig(s))
+	js := natsPublisher(t, s)
+
+	msg := nats.NewMsg("anyker.telegram.telegram:123")
+	msg.Data = []byte("hello")
+	msg.Header.Set(domain.CorrelationIDHeader, "corr-1")
+	_, err := js.PublishMsg(context.Background(), msg)
+	require.NoError(t, err)
+	message := receive(t, messages)
+
+	assert.Equal(t, "telegram:123", message.Key)
+	assert.Equal(t, []byte("hello"), message.Content)
+	// the topic is the stream, the subject ends with the key
+	assert.Equal(t, "anyker", message.Topic)
+	assert.Equal(t, int64(1), message.Offset)
+	assert.False(t, message.Timestamp.IsZero())
+	assert.Equal(t, map[string]string{domain.CorrelationIDHeader: "corr-1"}, message.Headers)
+	assert.NoError(t, consumer.Ready())
+	assert.NoError(t, consumer.Live())
+
+	// the stream is created with the default subjects and the consumer is durable
+	stream, err := js.Stream(context.Background(), "anyker")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"anyker.>"}, stream.CachedInfo().Config.Subjects)
+	info, err := stream.Consumer(context.Background(), "anyker")
+	require.NoError(t, err)
+	assert.Equal(t, "anyker", info.CachedInfo().Config.Durable)
+
+	require.NoError(t, consumer.Commit(context.Background(), *message))
+	assert.EqualError(t, consumer.Commit(context.Background(), *message), "message 1 is not in flight")
+	noMessage(t, messages)
+}
+
+func TestNATSConsumer_Subjects(t *testing.T) {
+	s := runNATSServer(t)
+	js := natsPublisher(t, s)
+	_, err := js.CreateStream(context.Background(), jetstream.StreamConfig{Name: "anyker", Subjects: []string{"anyker.>"}})
+	require.NoError(t, err)
+	cfg := natsConfig(s)
+	cfg.NATSSubjects = []string{"anyker.telegram.*", "anyker.whatsapp.>"}
+	_, messages := startNATSConsumer(t, cfg)
+
+	for _, subject := range []string{"anyker.email.email:1", "anyker.telegram.telegram:2", "anyker.whatsapp.eu.whatsapp:3"} {
+		_, err := js.Publish(context.Background(), subject, nil)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, "telegram:2", receive(t, messages).Key)
+	assert.Equal(t, "whatsapp:3", receive(t, messages).Key)
+	noMessage(t, messages)
+}
+
+func TestNATSConsumer_Reject(t *testing.T) {
+	s := runNATSServer(t)
+	consumer, messages := startNATSConsumer(t, natsConfig(s))
+	js := natsPublisher(t, s)
+	_, err := js.Publish(context.Background(), "anyker.telegram.telegram:1", nil)
+	require.NoError(t, err)
+
+	// nacked messages are delivered again until max deliver
+	first := receive(t, messages)
+	require.NoError(t, consumer.Reject(context.Background(), *first))
+	second := receive(t, messages)
+	assert.Equal(t, first.Offset, second.Offset)
+	require.NoError(t, consumer.Reject(context.Background(), *second))
+	noMessage(t, messages)
+
+	info, err := consumer.consumer.Info(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, info.NumAckPending)
+	assert.Zero(t, info.NumPending)
+}
+
+func TestNATSConsumer_AckWait(t *testing.T) {
+	s := runNATSServer(t)
+	cfg := natsConfig(s)
+	cfg.NATSAckWait = 200 * time.Millisecond
+	consumer, messages := startNATSConsumer(t, cfg)
+	js := natsPublisher(t, s)
+	_, err := js.Publish(context.Background(), "anyker.telegram.telegram:1", nil)
+	require.NoError(t, err)
+
+	// a message in flight is kept in progress past the ack wait and not delivered again
+	message := receive(t, messages)
+	time.Sleep(3 * cfg.NATSAckWait)
+	noMessage(t, messages)
+
+	require.NoError(t, consumer.Commit(context.Background(), *message))
+	noMessage(t, messages)
+}
+
+func TestNATSConsumer_DeliveredAgainInFlight(t *testing.T) {
+	s := runNATSServer(t)
+	cfg := natsConfig(s)
+	cfg.NATSAckWait = 200 * time.Millisecond
+	created, err := NewNATSConsumer(cfg)
+	require.NoError(t, err)
+	consumer := created.(*NATSConsumer)
+	defer consumer.Close()
+	// without heartbeat in time, the server delivers the message in flight again
+	consumer.ackWait = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	messages := make(chan *domain.Message)
+	go func() { _ = consumer.Consume(ctx, messages) }()
+	js := natsPublisher(t, s)
+	_, err = js.Publish(context.Background(), "anyker.telegram.telegram:1", nil)
+	require.NoError(t, err)
+	message := receive(t, messages)
+
+	// the redelivery is skipped and the first delivery can still be acked
+	time.Sleep(3 * cfg.NATSAckWait)
+	noMessage(t, messages)
+	require.NoError(t, consumer.Commit(context.Background(), *message))
+}
+
+func TestNATSConsumer_Errors(t *testing.T) {
+	t.Run("no server", func(t *testing.T) {
+		_, err := NewNATSConsumer(config.Config{NATSURL: "nats://127.0.0.1:1"})
+
+		assert.ErrorContains(t, err, "failed to connect to NATS server")
+	})
+
+	t.Run("closed connection", func(t *testing.T) {
+		s := runNATSServer(t)
+		consumer, err := NewNATSConsumer(natsConfig(s))
+		require.NoError(t, err)
+
+		require.NoError(t, consumer.Close())
+
+		assert.EqualError(t, consumer.(*NATSConsumer).Live(), "NATS connection is closed")
+		assert.EqualError(t, consumer.(*NATSConsumer).Ready(), "NATS consumer is not consuming")
+	})
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"sync"
+	"time"
+)
+
+// offsetTracker keeps track of the messages in flight of every partition,
+// so an offset is only committed once every previous message of its partition has been processed,
+// even when messages are processed concurrently and complete out of order.
+type offsetTracker struct {
+	mu         sync.Mutex
+	partitions map[partitionKey]*partitionOffsets
+}
+
+// partitionOffsets holds the offsets in flight of a partition.
+type partitionOffsets struct {
+	// offsets in consumption order
+	offsets []int64
+	// done tells whether each offset in flight has been processed
+	done map[int64]bool
+	// failedAt is when the first message in flight that could not be processed failed, zero if none did.
+	// The offsets of the partition are not committed anymore from failedOffset until it is revoked.
+	failedAt     time.Time
+	failedOffset int64
+}
+
+// stuckPartition describes a partition whose offsets can't be committed, see stuck.
+type stuckPartition struct {
+	key partitionKey
+	// offset is the oldest message in flight and pending their number
+	offset  int64
+	pending int
+	// failedOffset is the first message that could not be processed, if failed, and age how long ago it failed
+	failed       bool
+	failedOffset int64
+	age          time.Duration
+}
+
+// track registers a consumed message as in flight.
+func (t *offsetTracker) track(key partitionKey, offset int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.partitions == nil {
+		t.partitions = make(map[partitionKey]*partitionOffsets)
+	}
+	p, ok := t.partitions[key]
+	if !ok {
+		p = &partitionOffsets{done: make(map[int64]bool)}
+		t.partitions[key] = p
+	}
+	p.offsets = append(p.offsets, offset)
+	p.done[offset] = false
+}
+
+// markDone marks a message as processed. It returns the offset to commit for its partition,
+// that is the offset following the last message processed without gaps, and false if there is nothing to commit.
+// Messages that are not in flight, e.g. because their partition has been revoked, are ignored.
+func (t *offsetTracker) markDone(key partitionKey, offset int64) (int64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.partitions[key]
+	if !ok {
+		return 0, false
+	}
+	if _, ok := p.done[offset]; !ok {
+		return 0, false
+	}
+	p.done[offset] = true
+
+	committable := int64(-1)
+	for len(p.offsets) > 0 && p.done[p.offsets[0]] {
+		committable = p.offsets[0]
+		delete(p.done, committable)
+		p.offsets = p.offsets[1:]
+	}
+	if committable < 0 {
+		return 0, false
+	}
+	return committable + 1, true
+}
+
+// fail records that a message in flight could not be processed, so its offset and the following ones
+// of its partition won't be committed. It returns true for the first failure of the partition.
+func (t *offsetTracker) fail(key partitionKey, offset int64, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.partitions[key]
+	if !ok {
+		return false
+	}
+	if done, ok := p.done[offset]; !ok || done || !p.failedAt.IsZero() {
+		return false
+	}
+	p.failedAt = now
+	p.failedOffset = offset
+	return true
+}
+
+// stuck returns the first partition with at least maxPending messages in flight, or whose offsets have not been
+// committed for maxAge since a message failed, a zero limit being ignored. The offsets of such a partition can't
+// be committed, e.g. because a message failed without a dead-letter topic, while every message consumed after it
+// is kept in memory.
+func (t *offsetTracker) stuck(maxPending int, maxAge time.Duration, now time.Time) (stuckPartition, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key, p := range t.partitions {
+		if len(p.offsets) == 0 {
+			continue
+		}
+		var age time.Duration
+		if !p.failedAt.IsZero() {
+			age = now.Sub(p.failedAt)
+		}
+		if (maxPending > 0 && len(p.offsets) >= maxPending) || (maxAge > 0 && !p.failedAt.IsZero() && age >= maxAge) {
+			return stuckPartition{
+				key:          key,
+				offset:       p.offsets[0],
+				pending:      len(p.offsets),
+				failed:       !p.failedAt.IsZero(),
+				failedOffset: p.failedOffset,
+				age:          age,
+			}, true
+		}
+	}
+	return stuckPartition{}, false
+}
+
+// forget drops the messages in flight of a partition, when it is no longer assigned to this consumer.
+func (t *offsetTracker) forget(key partitionKey) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.partitions, key)
+}
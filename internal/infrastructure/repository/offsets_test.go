@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOffsetTracker(t *testing.T) {
+	key := partitionKey{topic: "test-topic", partition: 0}
+	other := partitionKey{topic: "test-topic", partition: 1}
+
+	t.Run("in order", func(t *testing.T) {
+		var tracker offsetTracker
+		tracker.track(key, 10)
+		tracker.track(key, 11)
+
+		offset, ok := tracker.markDone(key, 10)
+		assert.True(t, ok)
+		assert.Equal(t, int64(11), offset)
+
+		offset, ok = tracker.markDone(key, 11)
+		assert.True(t, ok)
+		assert.Equal(t, int64(12), offset)
+	})
+
+	t.Run("out of order", func(t *testing.T) {
+		var tracker offsetTracker
+		tracker.track(key, 10)
+		tracker.track(key, 12) // offsets may have gaps, e.g. compacted topics
+		tracker.track(key, 13)
+
+		_, ok := tracker.markDone(key, 13)
+		assert.False(t, ok)
+		_, ok = tracker.markDone(key, 12)
+		assert.False(t, ok)
+
+		offset, ok := tracker.markDone(key, 10)
+		assert.True(t, ok)
+		assert.Equal(t, int64(14), offset)
+	})
+
+	t.Run("partitions are independent", func(t *testing.T) {
+		var tracker offsetTracker
+		tracker.track(key, 10)
+		tracker.track(other, 5)
+
+		offset, ok := tracker.markDone(other, 5)
+		assert.True(t, ok)
+		assert.Equal(t, int64(6), offset)
+
+		_, ok = tracker.markDone(other, 5)
+		assert.False(t, ok) // already committed
+	})
+
+	t.Run("untracked messages are ignored", func(t *testing.T) {
+		var tracker offsetTracker
+		_, ok := tracker.markDone(key, 10)
+		assert.False(t, ok)
+
+		tracker.track(key, 10)
+		_, ok = tracker.markDone(key, 9)
+		assert.False(t, ok)
+	})
+
+	t.Run("forget", func(t *testing.T) {
+		var tracker offsetTracker
+		tracker.track(key, 10)
+		tracker.forget(key)
+
+		_, ok := tracker.markDone(key, 10)
+		assert.False(t, ok)
+	})
+}
+
+func TestOffsetTracker_Stuck(t *testing.T) {
+	key := partitionKey{topic: "test-topic", partition: 0}
+	var tracker offsetTracker
+	tracker.track(key, 10)
+	tracker.track(key, 11)
+
+	now := time.Now()
+	_, ok := tracker.stuck(3, time.Minute, now)
+	assert.False(t, ok)
+
+	// 10 failed and is never marked as done
+	assert.True(t, tracker.fail(key, 10, now))
+	assert.False(t, tracker.fail(key, 10, now.Add(time.Second)), "only the first failure is reported")
+	_, ok = tracker.markDone(key, 11)
+	assert.False(t, ok)
+	_, ok = tracker.stuck(3, time.Minute, now.Add(time.Second))
+	assert.False(t, ok)
+
+	// too long since the failure
+	stuck, ok := tracker.stuck(0, time.Minute, now.Add(time.Minute))
+	assert.True(t, ok)
+	assert.Equal(t, stuckPartition{key: key, offset: 10, pending: 2, failed: true, failedOffset: 10, age: time.Minute}, stuck)
+	_, ok = tracker.stuck(3, 0, now.Add(time.Hour))
+	assert.False(t, ok, "no age limit")
+
+	// too many messages in flight
+	tracker.track(key, 12)
+	stuck, ok = tracker.stuck(3, time.Minute, now.Add(time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, key, stuck.key)
+	assert.Equal(t, int64(10), stuck.offset)
+	assert.Equal(t, 3, stuck.pending)
+
+	// the failures of revoked partitions are forgotten
+	tracker.forget(key)
+	assert.False(t, tracker.fail(key, 10, now))
+	_, ok = tracker.stuck(1, time.Minute, now.Add(time.Hour))
+	assert.False(t, ok)
+}
@@ -0,0 +1,270 @@
+package repository
+
+import (
+	"anyker/config"
+	"anyker/internal/domain"
+	"anyker/internal/metrics"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// redisBlock is how long a read waits for new entries, so the context is checked regularly.
+const redisBlock = time.Second
+
+// redisAckTimeout is how long an ack may take. Acks are not cancelled by the shutdown,
+// so the entries forwarded while shutting down are not read again.
+const redisAckTimeout = 5 * time.Second
+
+// RedisConsumer implements domain.ConsumerRepository for Redis Streams with a consumer group.
+// Entries are acked once forwarded. Entries that could not be forwarded stay pending,
+// they are read again on restart and claimed by a consumer of the group once they have been idle long enough.
+type RedisConsumer struct {
+	client       *redis.Client
+	stream       string
+	group        string
+	consumer     string
+	payloadField string
+	keyField     string
+	batchSize    int64
+	claimMinIdle time.Duration
+	block        time.Duration
+	stallTimeout time.Duration
+	consuming    atomic.Bool
+	// lastPoll is the time of the last successful read, in Unix nanoseconds, 0 before the first one.
+	lastPoll atomic.Int64
+	// inflight holds the IDs of the entries sent to the workers and not acked or rejected yet,
+	// so they are not claimed and sent again while still being forwarded.
+	inflight sync.Map
+}
+
+// NewRedisConsumer connects to Redis and creates a consumer of the stream.
+// The consumer group is created when the consumption starts.
+func NewRedisConsumer(config config.Config) (domain.ConsumerRepository, error) {
+	options, err := redis.ParseURL(config.RedisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	client := redis.NewClient(options)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	consumer := config.RedisConsumer
+	if consumer == "" {
+		consumer = config.NanobotName
+	}
+	return &RedisConsumer{
+		client:       client,
+		stream:       config.RedisStream,
+		group:        config.RedisGroup,
+		consumer:     consumer,
+		payloadField: config.RedisPayloadField,
+		keyField:     config.RedisKeyField,
+		batchSize:    int64(config.RedisBatchSize),
+		claimMinIdle: config.RedisClaimMinIdle,
+		block:        redisBlock,
+		stallTimeout: config.HealthStallTimeout,
+	}, nil
+}
+
+// Consume creates the consumer group if needed, then reads the entries of the stream and sends them
+// to the provided channel until the context is cancelled. The entries pending for this consumer,
+// read before a restart and not acked, are sent first, and the idle entries of the group are claimed
+// only after them, so they are not sent twice.
+func (c *RedisConsumer) Consume(ctx context.Context, messages chan<- *domain.Message) error {
+	defer close(messages)
+
+	err := c.client.XGroupCreateMkStream(ctx, c.stream, c.group, "$").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("failed to create consumer group %s: %w", c.group, err)
+	}
+	c.consuming.Store(true)
+	defer c.consuming.Store(false)
+
+	// "0" reads the pending entries of the consumer, ">" the new ones
+	start := "0"
+	var lastClaim time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		if start == ">" && c.claimMinIdle > 0 && time.Since(lastClaim) >= c.claimMinIdle {
+			if err := c.claim(ctx, messages); err != nil && ctx.Err() == nil {
+				log.Error().Err(err).Msg("failed to claim pending entries")
+			}
+			lastClaim = time.Now()
+		}
+
+		streams, err := c.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    c.group,
+			Consumer: c.consumer,
+			Streams:  []string{c.stream, start},
+			Count:    c.batchSize,
+			Block:    c.block,
+		}).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			if ctx.Err() != nil {
+				return nil // Graceful exit on context cancellation
+			}
+			log.Error().Err(err).Msg("failed to read from Redis stream")
+			select {
+			case <-ctx.Done():
+			case <-time.After(c.block):
+			}
+			continue
+		}
+		c.lastPoll.Store(time.Now().UnixNano())
+
+		var entries []redis.XMessage
+		if len(streams) > 0 {
+			entries = streams[0].Messages
+		}
+		if start != ">" {
+			if len(entries) == 0 {
+				start = ">"
+			} else {
+				start = entries[len(entries)-1].ID
+			}
+		}
+		for _, entry := range entries {
+			c.send(messages, entry)
+		}
+	}
+}
+
+// claim takes over the entries of the group pending for more than claimMinIdle, and sends them
+// but the ones still in flight.
+func (c *RedisConsumer) claim(ctx context.Context, messages chan<- *domain.Message) error {
+	start := "0-0"
+	for {
+		entries, next, err := c.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   c.stream,
+			Group:    c.group,
+			Consumer: c.consumer,
+			MinIdle:  c.claimMinIdle,
+			Start:    start,
+			Count:    c.batchSize,
+		}).Result()
+		if err != nil {
+			return err
+		}
+		var claimed int
+		for _, entry := range entries {
+			if _, ok := c.inflight.Load(entry.ID); ok {
+				// still being forwarded, e.g. slowly or waiting for a retry
+				continue
+			}
+			claimed++
+			c.send(messages, entry)
+		}
+		if claimed > 0 {
+			log.Info().Msgf("claimed %d pending entries of stream %s", claimed, c.stream)
+		}
+		if next == "0-0" {
+			return nil
+		}
+		start = next
+	}
+}
+
+// send converts an entry to a message and sends it.
+func (c *RedisConsumer) send(messages chan<- *domain.Message, entry redis.XMessage) {
+	message := redisMessage(c.stream, entry, c.payloadField, c.keyField)
+	log.Debug().Msgf("headers received from Redis entry %v", message.Headers)
+	log.Debug().Msgf("key receive from Redis entry %v", message.Key)
+	log.Debug().Msgf("payload receive from Redis entry %v", string(message.Content))
+
+	metrics.MessagesConsumed.WithLabelValues(message.Topic).Inc()
+	c.inflight.Store(message.ID, struct{}{})
+	messages <- message
+}
+
+// redisMessage converts a stream entry to a message: the payload field is the content, the key field the key,
+// and the other fields are headers. The entry ID is the message ID, and its time part the timestamp.
+func redisMessage(stream string, entry redis.XMessage, payloadField, keyField string) *domain.Message {
+	message := &domain.Message{
+		Headers: make(map[string]string, len(entry.Values)),
+		Topic:   stream,
+		ID:      entry.ID,
+	}
+	for field, value := range entry.Values {
+		v := fmt.Sprint(value)
+		switch field {
+		case payloadField:
+			message.Content = []byte(v)
+		case keyField:
+			message.Key = v
+		default:
+			message.Headers[field] = v
+		}
+	}
+	milliseconds, _, _ := strings.Cut(entry.ID, "-")
+	if ms, err := strconv.ParseInt(milliseconds, 10, 64); err == nil {
+		message.Timestamp = time.UnixMilli(ms)
+	}
+	return message
+}
+
+// Commit acks an entry, so it is removed from the pending entries of the group.
+// The ack is not cancelled with the context, an entry forwarded as the shutdown starts is acked too.
+func (c *RedisConsumer) Commit(ctx context.Context, message domain.Message) error {
+	defer c.inflight.Delete(message.ID)
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), redisAckTimeout)
+	defer cancel()
+	if err := c.client.XAck(ctx, c.stream, c.group, message.ID).Err(); err != nil {
+		return fmt.Errorf("failed to ack entry %s: %w", message.ID, err)
+	}
+	return nil
+}
+
+// Reject leaves an entry that could not be forwarded pending, it is no longer in flight
+// so it is claimed again once idle for longer than claimMinIdle.
+func (c *RedisConsumer) Reject(_ context.Context, message domain.Message) error {
+	c.inflight.Delete(message.ID)
+	return nil
+}
+
+// Live fails when the stream could not be read for longer than the stall timeout.
+func (c *RedisConsumer) Live() error {
+	lastPoll := c.lastPoll.Load()
+	if lastPoll == 0 || c.stallTimeout <= 0 {
+		// not started yet
+		return nil
+	}
+	if since := time.Since(time.Unix(0, lastPoll)); since > c.stallTimeout {
+		return fmt.Errorf("redis consumer has not read the stream for %s", since.Round(time.Second))
+	}
+	return nil
+}
+
+// Ready fails until the stream is being read.
+func (c *RedisConsumer) Ready() error {
+	if !c.consuming.Load() {
+		return errors.New("redis consumer is not consuming")
+	}
+	if c.lastPoll.Load() == 0 {
+		return errors.New("redis consumer has not read the stream yet")
+	}
+	return nil
+}
+
+// Close closes the connections to Redis.
+func (c *RedisConsumer) Close() error {
+	return c.client.Close()
+}
@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"anyker/config"
+	"anyker/internal/domain"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func redisConfig(s *miniredis.Miniredis) config.Config {
+	return config.Config{
+		RedisURL:           "redis://" + s.Addr(),
+		RedisStream:        "anyker",
+		RedisGroup:         "anyker-group",
+		RedisPayloadField:  "payload",
+		RedisKeyField:      "key",
+		RedisBatchSize:     10,
+		NanobotName:        "anyker-nanobot-1",
+		HealthStallTimeout: time.Minute,
+	}
+}
+
+// startRedisConsumer creates a consumer and consumes in the background until stopped or the end of the test.
+func startRedisConsumer(t *testing.T, cfg config.Config) (*RedisConsumer, <-chan *domain.Message, func()) {
+	t.Helper()
+	repo, err := NewRedisConsumer(cfg)
+	require.NoError(t, err)
+	consumer := repo.(*RedisConsumer)
+	consumer.block = 50 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	messages := make(chan *domain.Message)
+	done := make(chan error)
+	go func() { done <- consumer.Consume(ctx, messages) }()
+	var stopped bool
+	stop := func() {
+		if stopped {
+			return
+		}
+		stopped = true
+		cancel()
+		for range messages {
+		}
+		assert.NoError(t, <-done)
+		consumer.Close()
+	}
+	t.Cleanup(stop)
+	return consumer, messages, stop
+}
+
+// waitReady waits until the consumer group has been created.
+func waitReady(t *testing.T, consumer *RedisConsumer) {
+	t.Helper()
+	assert.Eventually(t, func() bool { return consumer.Ready() == nil }, time.Second, 10*time.Millisecond)
+}
+
+func xadd(t *testing.T, client *redis.Client, values map[string]interface{}) string {
+	t.Helper()
+	id, err := client.XAdd(context.Background(), &redis.XAddArgs{Stream: "anyker", Values: values}).Result()
+	require.NoError(t, err)
+	return id
+}
+
+func TestRedisConsumer_Consume(t *testing.T) {
+	s := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	consumer, messages, _ := startRedisConsumer(t, redisConfig(s))
+	waitReady(t, consumer)
+
+	id := xadd(t, client, map[string]interface{}{"payload": `{"text":"hello"}`, "key": "telegram:123", "correlation_id": "corr-1"})
+	message := receive(t, messages)
+
+	assert.Equal(t, id, message.ID)
+	assert.Equal(t, "telegram:123", message.Key)
+	assert.Equal(t, []byte(`{"text":"hello"}`), message.Content)
+	assert.Equal(t, "anyker", message.Topic)
+	assert.Equal(t, map[string]string{"correlation_id": "corr-1"}, message.Headers)
+	assert.WithinDuration(t, time.Now(), message.Timestamp, time.Minute)
+	assert.NoError(t, consumer.Live())
+
+	pending, err := client.XPending(context.Background(), "anyker", "anyker-group").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), pending.Count)
+	assert.Equal(t, map[string]int64{"anyker-nanobot-1": 1}, pending.Consumers)
+
+	require.NoError(t, consumer.Commit(context.Background(), *message))
+	pending, err = client.XPending(context.Background(), "anyker", "anyker-group").Result()
+	require.NoError(t, err)
+	assert.Zero(t, pending.Count)
+}
+
+func TestRedisConsumer_Consume_Pending(t *testing.T) {
+	s := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	cfg := redisConfig(s)
+	cfg.RedisConsumer = "forwarder-1"
+	consumer, messages, stop := startRedisConsumer(t, cfg)
+	waitReady(t, consumer)
+	first := xadd(t, client, map[string]interface{}{"key": "telegram:1"})
+	second := xadd(t, client, map[string]interface{}{"key": "telegram:2"})
+	assert.Equal(t, first, receive(t, messages).ID)
+	acked := receive(t, messages)
+	require.NoError(t, consumer.Commit(context.Background(), *acked))
+	stop()
+
+	// after a restart, the entries read and not acked are read again, then the new ones
+	third := xadd(t, client, map[string]interface{}{"key": "telegram:3"})
+	_, messages, _ = startRedisConsumer(t, cfg)
+
+	assert.Equal(t, first, receive(t, messages).ID)
+	assert.Equal(t, third, receive(t, messages).ID)
+	assert.NotEqual(t, second, third)
+}
+
+func TestRedisConsumer_Claim(t *testing.T) {
+	s := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	require.NoError(t, client.XGroupCreateMkStream(context.Background(), "anyker", "anyker-group", "$").Err())
+	id := xadd(t, client, map[string]interface{}{"key": "telegram:1"})
+	// a consumer that crashed after reading the entry
+	_, err := client.XReadGroup(context.Background(), &redis.XReadGroupArgs{
+		Group: "anyker-group", Consumer: "crashed", Streams: []string{"anyker", ">"},
+	}).Result()
+	require.NoError(t, err)
+
+	cfg := redisConfig(s)
+	cfg.RedisClaimMinIdle = 100 * time.Millisecond
+	consumer, messages, _ := startRedisConsumer(t, cfg)
+	message := receive(t, messages)
+
+	assert.Equal(t, id, message.ID)
+	require.NoError(t, consumer.Commit(context.Background(), *message))
+	pending, err := client.XPending(context.Background(), "anyker", "anyker-group").Result()
+	require.NoError(t, err)
+	assert.Zero(t, pending.Count)
+}
+
+func TestRedisConsumer_Claim_AfterPending(t *testing.T) {
+	s := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	cfg := redisConfig(s)
+	cfg.RedisConsumer = "forwarder-1"
+	consumer, messages, stop := startRedisConsumer(t, cfg)
+	waitReady(t, consumer)
+	first := xadd(t, client, map[string]interface{}{"key": "telegram:1"})
+	second := xadd(t, client, map[string]interface{}{"key": "telegram:2"})
+	assert.Equal(t, first, receive(t, messages).ID)
+	assert.Equal(t, second, receive(t, messages).ID)
+	stop()
+
+	// the pending entries are idle long enough to be claimed, but they are only read once from the history
+	time.Sleep(150 * time.Millisecond)
+	cfg.RedisClaimMinIdle = 100 * time.Millisecond
+	_, messages, _ = startRedisConsumer(t, cfg)
+
+	assert.Equal(t, first, receive(t, messages).ID)
+	assert.Equal(t, second, receive(t, messages).ID)
+	noMessage(t, messages)
+}
+
+func TestRedisConsumer_Claim_Inflight(t *testing.T) {
+	s := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	cfg := redisConfig(s)
+	cfg.RedisClaimMinIdle = 50 * time.Millisecond
+	consumer, messages, _ := startRedisConsumer(t, cfg)
+	waitReady(t, consumer)
+	id := xadd(t, client, map[string]interface{}{"key": "telegram:1"})
+	message := receive(t, messages)
+	assert.Equal(t, id, message.ID)
+
+	// an entry still being forwarded is not claimed again, even when idle for longer than the minimum
+	noMessage(t, messages)
+
+	// once rejected, it is claimed again
+	require.NoError(t, consumer.Reject(context.Background(), *message))
+	assert.Equal(t, id, receive(t, messages).ID)
+}
+
+func TestRedisConsumer_Commit_Cancelled(t *testing.T) {
+	s := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	consumer, messages, _ := startRedisConsumer(t, redisConfig(s))
+	waitReady(t, consumer)
+	xadd(t, client, map[string]interface{}{"key": "telegram:1"})
+	message := receive(t, messages)
+
+	// a message forwarded as the shutdown starts is acked anyway
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, consumer.Commit(ctx, *message))
+
+	pending, err := client.XPending(context.Background(), "anyker", "anyker-group").Result()
+	require.NoError(t, err)
+	assert.Zero(t, pending.Count)
+}
+
+func TestRedisMessage(t *testing.T) {
+	message := redisMessage("anyker", redis.XMessage{
+		ID:     "1700000000123-4",
+		Values: map[string]interface{}{"body": "hello", "conversation": "whatsapp:9", "type": "text"},
+	}, "body", "conversation")
+
+	assert.Equal(t, &domain.Message{
+		Content:   []byte("hello"),
+		Headers:   map[string]string{"type": "text"},
+		Key:       "whatsapp:9",
+		Topic:     "anyker",
+		ID:        "1700000000123-4",
+		Timestamp: time.UnixMilli(1700000000123),
+	}, message)
+}
+
+func TestRedisConsumer_Health(t *testing.T) {
+	s := miniredis.RunT(t)
+	repo, err := NewRedisConsumer(redisConfig(s))
+	require.NoError(t, err)
+	consumer := repo.(*RedisConsumer)
+	defer consumer.Close()
+
+	assert.NoError(t, consumer.Live())
+	assert.EqualError(t, consumer.Ready(), "redis consumer is not consuming")
+
+	consumer.lastPoll.Store(time.Now().Add(-2 * time.Minute).UnixNano())
+	assert.ErrorContains(t, consumer.Live(), "redis consumer has not read the stream for")
+}
+
+func TestNewRedisConsumer_Errors(t *testing.T) {
+	_, err := NewRedisConsumer(config.Config{RedisURL: "localhost:6379"})
+	assert.ErrorContains(t, err, "invalid Redis URL")
+
+	_, err = NewRedisConsumer(config.Config{RedisURL: "redis://127.0.0.1:1"})
+	assert.ErrorContains(t, err, "failed to connect to Redis")
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"anyker/config"
+	"anyker/internal/domain"
+	"context"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"strconv"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// Headers of the replies.
+const (
+	replyStatusCodeHeader  = "reply_status_code"
+	replyContentTypeHeader = "reply_content_type"
+	replyNanobotNameHeader = "reply_nanobot_name"
+	replyTimestampHeader   = "reply_timestamp"
+)
+
+// ReplyProducer is a Kafka producer that implements the ReplyRepository interface.
+type ReplyProducer struct {
+	producer KafkaProducer
+	topic    string
+}
+
+// NewReplyProducer creates a new Kafka producer for the configured reply topic.
+func NewReplyProducer(config config.Config) (domain.ReplyRepository, error) {
+	configMap, err := newKafkaConfigMap(config, nil)
+	if err != nil {
+		return nil, err
+	}
+	p, err := kafka.NewProducer(configMap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reply producer: %w", err)
+	}
+	go logProducerEvents("reply", p.Events())
+
+	return &ReplyProducer{
+		producer: p,
+		topic:    config.ReplyTopic,
+	}, nil
+}
+
+// Publish produces the response content to the reply topic, keyed with the key of the original message
+// so the replies of a conversation stay in order, with the correlation ID and the response metadata as headers.
+func (p *ReplyProducer) Publish(ctx context.Context, reply domain.Reply) error {
+	correlationID := reply.Message.CorrelationID
+	if correlationID == "" {
+		correlationID = reply.Message.Headers[domain.CorrelationIDHeader]
+	}
+	headers := []kafka.Header{
+		{Key: domain.CorrelationIDHeader, Value: []byte(correlationID)},
+		{Key: replyStatusCodeHeader, Value: []byte(strconv.Itoa(reply.StatusCode))},
+		{Key: replyContentTypeHeader, Value: []byte(reply.ContentType)},
+		{Key: replyNanobotNameHeader, Value: []byte(reply.NanobotName)},
+		{Key: replyTimestampHeader, Value: []byte(reply.Timestamp.UTC().Format(time.RFC3339Nano))},
+	}
+
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &p.topic, Partition: kafka.PartitionAny},
+		Key:            []byte(reply.Message.Key),
+		Value:          reply.Content,
+		Headers:        headers,
+	}
+	if err := produce(ctx, p.producer, msg); err != nil {
+		return err
+	}
+	log.Ctx(ctx).Debug().Msgf("reply published to topic %s", p.topic)
+
+	return nil
+}
+
+// Close flushes pending replies and closes the Kafka producer.
+func (p *ReplyProducer) Close() error {
+	if remaining := p.producer.Flush(flushTimeoutMs); remaining > 0 {
+		log.Warn().Msgf("%d replies were not delivered before closing", remaining)
+	}
+	p.producer.Close()
+	return nil
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"anyker/config"
+	"anyker/internal/domain"
+	"anyker/internal/infrastructure/repository/mocks"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestNewReplyProducer(t *testing.T) {
+	cfg := config.Config{
+		KafkaBroker: "localhost:9092",
+		ReplyTopic:  "test-replies",
+	}
+
+	producer, err := NewReplyProducer(cfg)
+	assert.NoError(t, err)
+	assert.NotNil(t, producer)
+	assert.Equal(t, "test-replies", producer.(*ReplyProducer).topic)
+
+	// close without flushing, there is no broker to deliver to
+	producer.(*ReplyProducer).producer.Close()
+}
+
+func TestReplyProducer_Publish(t *testing.T) {
+	reply := domain.Reply{
+		Message: domain.Message{
+			Content:       []byte("message1"),
+			Headers:       map[string]string{"type": "text"},
+			Key:           "telegram:user-123",
+			CorrelationID: "generated",
+		},
+		StatusCode:  200,
+		ContentType: "application/json",
+		Content:     []byte(`{"text":"hello"}`),
+		NanobotName: "test-nanobot",
+		Timestamp:   time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	t.Run("success", func(t *testing.T) {
+		mockKafkaProducer := new(mocks.KafkaProducer)
+		producer := &ReplyProducer{producer: mockKafkaProducer, topic: "test-replies"}
+		defer mockKafkaProducer.AssertExpectations(t)
+
+		var produced *kafka.Message
+		mockKafkaProducer.On("Produce", mock.AnythingOfType("*kafka.Message"), mock.Anything).
+			Run(func(args mock.Arguments) {
+				produced = args.Get(0).(*kafka.Message)
+				args.Get(1).(chan kafka.Event) <- produced
+			}).Return(nil).Once()
+
+		err := producer.Publish(context.Background(), reply)
+		assert.NoError(t, err)
+
+		assert.Equal(t, "test-replies", *produced.TopicPartition.Topic)
+		assert.Equal(t, "telegram:user-123", string(produced.Key))
+		assert.Equal(t, `{"text":"hello"}`, string(produced.Value))
+
+		headers := make(map[string]string)
+		for _, h := range produced.Headers {
+			headers[h.Key] = string(h.Value)
+		}
+		assert.Equal(t, map[string]string{
+			"correlation_id":       "generated",
+			replyStatusCodeHeader:  "200",
+			replyContentTypeHeader: "application/json",
+			replyNanobotNameHeader: "test-nanobot",
+			replyTimestampHeader:   "2025-01-01T12:00:00Z",
+		}, headers)
+	})
+
+	t.Run("delivery error", func(t *testing.T) {
+		mockKafkaProducer := new(mocks.KafkaProducer)
+		producer := &ReplyProducer{producer: mockKafkaProducer, topic: "test-replies"}
+		defer mockKafkaProducer.AssertExpectations(t)
+
+		mockKafkaProducer.On("Produce", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				msg := args.Get(0).(*kafka.Message)
+				msg.TopicPartition.Error = kafka.NewError(kafka.ErrMsgTimedOut, "Local: Message timed out", false)
+				args.Get(1).(chan kafka.Event) <- msg
+			}).Return(nil).Once()
+
+		err := producer.Publish(context.Background(), reply)
+		assert.ErrorContains(t, err, "failed to deliver message")
+	})
+
+	t.Run("produce error", func(t *testing.T) {
+		mockKafkaProducer := new(mocks.KafkaProducer)
+		producer := &ReplyProducer{producer: mockKafkaProducer, topic: "test-replies"}
+		defer mockKafkaProducer.AssertExpectations(t)
+
+		mockKafkaProducer.On("Produce", mock.Anything, mock.Anything).Return(errors.New("queue full")).Once()
+
+		err := producer.Publish(context.Background(), reply)
+		assert.ErrorContains(t, err, "queue full")
+	})
+}
+
+func TestReplyProducer_Close(t *testing.T) {
+	mockKafkaProducer := new(mocks.KafkaProducer)
+	producer := &ReplyProducer{producer: mockKafkaProducer, topic: "test-replies"}
+	defer mockKafkaProducer.AssertExpectations(t)
+
+	mockKafkaProducer.On("Flush", flushTimeoutMs).Return(0).Once()
+	mockKafkaProducer.On("Close").Return().Once()
+
+	assert.NoError(t, producer.Close())
+}
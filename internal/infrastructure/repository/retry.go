@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"anyker/config"
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// retryPolicy decides whether a failed forward attempt is retried and how long to wait before retrying.
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	jitter      bool
+	statusCodes map[int]bool
+}
+
+// newRetryPolicy creates a retryPolicy from the forward retry configuration.
+func newRetryPolicy(config config.Config) retryPolicy {
+	statusCodes := make(map[int]bool, len(config.ForwardRetryStatusCodes))
+	for _, code := range config.ForwardRetryStatusCodes {
+		statusCodes[code] = true
+	}
+	maxAttempts := config.ForwardRetryMaxAttempts
+	if maxAttempts < 1 {
+		// at least the first attempt is always made
+		maxAttempts = 1
+	}
+	return retryPolicy{
+		maxAttempts: maxAttempts,
+		baseDelay:   config.ForwardRetryBaseDelay,
+		maxDelay:    config.ForwardRetryMaxDelay,
+		jitter:      config.ForwardRetryJitter,
+		statusCodes: statusCodes,
+	}
+}
+
+// retryable reports whether an attempt that failed with the given status code should be retried.
+// Transport errors (status code 0), timeouts of the attempt included, are always retried,
+// unless the context of the forward was cancelled or timed out.
+func (p retryPolicy) retryable(ctx context.Context, statusCode int) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if statusCode == 0 {
+		return true
+	}
+	return p.statusCodes[statusCode]
+}
+
+// delay returns how long to wait after the given failed attempt (1-based).
+// A Retry-After value sent by the server takes precedence over the exponential backoff,
+// both are capped at the configured maximum delay.
+func (p retryPolicy) delay(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return p.capped(retryAfter)
+	}
+	delay := p.baseDelay
+	for i := 1; i < attempt && delay > 0; i++ {
+		delay *= 2
+		if p.maxDelay > 0 && delay >= p.maxDelay {
+			break
+		}
+	}
+	if delay < 0 {
+		// overflow
+		delay = p.maxDelay
+	}
+	delay = p.capped(delay)
+
+	if p.jitter && delay > 0 {
+		// equal jitter: keep half of the delay and randomize the other half
+		half := delay / 2
+		delay = half + time.Duration(rand.Int63n(int64(delay-half)+1))
+	}
+	return delay
+}
+
+// capped limits a delay to the configured maximum delay, if any.
+func (p retryPolicy) capped(delay time.Duration) time.Duration {
+	if p.maxDelay > 0 && delay > p.maxDelay {
+		return p.maxDelay
+	}
+	return delay
+}
+
+// parseRetryAfter parses a Retry-After header value, either in seconds or as an HTTP date.
+// It returns 0 if the header is empty, invalid or in the past.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := date.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// sleep waits for the given duration or until the context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"anyker/config"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRetryPolicy(t *testing.T) {
+	t.Run("at least one attempt", func(t *testing.T) {
+		policy := newRetryPolicy(config.Config{})
+
+		assert.Equal(t, 1, policy.maxAttempts)
+	})
+
+	t.Run("status codes", func(t *testing.T) {
+		policy := newRetryPolicy(config.Config{ForwardRetryMaxAttempts: 5, ForwardRetryStatusCodes: []int{429, 503}})
+
+		assert.Equal(t, 5, policy.maxAttempts)
+		assert.True(t, policy.statusCodes[429])
+		assert.True(t, policy.statusCodes[503])
+		assert.False(t, policy.statusCodes[500])
+	})
+}
+
+func TestRetryPolicy_Retryable(t *testing.T) {
+	policy := newRetryPolicy(config.Config{ForwardRetryStatusCodes: []int{503}})
+
+	ctx := context.Background()
+
+	assert.True(t, policy.retryable(ctx, 0))
+	assert.True(t, policy.retryable(ctx, 503))
+	assert.False(t, policy.retryable(ctx, 500))
+
+	// shutting down
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.False(t, policy.retryable(cancelled, 0))
+	assert.False(t, policy.retryable(cancelled, 503))
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	t.Run("exponential without jitter", func(t *testing.T) {
+		policy := newRetryPolicy(config.Config{
+			ForwardRetryBaseDelay: 100 * time.Millisecond,
+			ForwardRetryMaxDelay:  time.Second,
+		})
+
+		assert.Equal(t, 100*time.Millisecond, policy.delay(1, 0))
+		assert.Equal(t, 200*time.Millisecond, policy.delay(2, 0))
+		assert.Equal(t, 400*time.Millisecond, policy.delay(3, 0))
+		assert.Equal(t, 800*time.Millisecond, policy.delay(4, 0))
+		assert.Equal(t, time.Second, policy.delay(5, 0))
+		assert.Equal(t, time.Second, policy.delay(100, 0))
+	})
+
+	t.Run("jitter stays within bounds", func(t *testing.T) {
+		policy := newRetryPolicy(config.Config{
+			ForwardRetryBaseDelay: 100 * time.Millisecond,
+			ForwardRetryMaxDelay:  time.Second,
+			ForwardRetryJitter:    true,
+		})
+
+		for i := 0; i < 100; i++ {
+			delay := policy.delay(2, 0)
+			assert.GreaterOrEqual(t, delay, 100*time.Millisecond)
+			assert.LessOrEqual(t, delay, 200*time.Millisecond)
+		}
+	})
+
+	t.Run("retry after takes precedence", func(t *testing.T) {
+		policy := newRetryPolicy(config.Config{
+			ForwardRetryBaseDelay: 100 * time.Millisecond,
+			ForwardRetryMaxDelay:  10 * time.Second,
+			ForwardRetryJitter:    true,
+		})
+
+		assert.Equal(t, 3*time.Second, policy.delay(1, 3*time.Second))
+		assert.Equal(t, 10*time.Second, policy.delay(1, time.Minute))
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Duration(0), parseRetryAfter("", now))
+	assert.Equal(t, 5*time.Second, parseRetryAfter("5", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("-5", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("invalid", now))
+	assert.Equal(t, 30*time.Second, parseRetryAfter(now.Add(30*time.Second).Format(http.TimeFormat), now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter(now.Add(-30*time.Second).Format(http.TimeFormat), now))
+}
+
+func TestSleep(t *testing.T) {
+	t.Run("waits", func(t *testing.T) {
+		assert.NoError(t, sleep(context.Background(), time.Millisecond))
+	})
+
+	t.Run("context cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		assert.ErrorIs(t, sleep(ctx, time.Minute), context.Canceled)
+	})
+}
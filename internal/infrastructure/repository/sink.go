@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"anyker/config"
+	"anyker/internal/domain"
+	"anyker/internal/infrastructure/client"
+	"fmt"
+	"strings"
+)
+
+// Types of the sinks messages are forwarded to.
+const (
+	SinkHTTP  = "http"
+	SinkGRPC  = "grpc"
+	SinkKafka = "kafka"
+)
+
+// NewSink creates the forward repository of the sink selected by the sink type, HTTP if empty,
+// forwarding to the destination: an API endpoint, a gRPC target or a Kafka topic, the configured one if empty.
+// The HTTP client is only used by the HTTP sink, the authenticator by the gRPC one, the HTTP client being
+// created with its own. The reply repository is optional, when nil the responses are discarded.
+func NewSink(
+	config config.Config,
+	destination string,
+	httpClient client.HttpClient,
+	authenticator client.Authenticator,
+	replyRepository domain.ReplyRepository) (domain.ForwardRepository, error) {
+	switch strings.ToLower(config.SinkType) {
+	case "", SinkHTTP:
+		if destination != "" {
+			config.APIEndpoint = destination
+		}
+		return NewForwardRepository(config, httpClient, replyRepository)
+	case SinkGRPC:
+		if destination != "" {
+			config.GRPCTarget = destination
+		}
+		conn, err := client.NewGRPCConn(config, authenticator)
+		if err != nil {
+			return nil, err
+		}
+		return NewGRPCForwardRepository(config, conn, replyRepository), nil
+	case SinkKafka:
+		return NewKafkaSink(config, destination)
+	default:
+		return nil, fmt.Errorf("unknown sink type: %s", config.SinkType)
+	}
+}
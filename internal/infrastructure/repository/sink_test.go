@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"anyker/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSink(t *testing.T) {
+	t.Run("http by default", func(t *testing.T) {
+		sink, err := NewSink(config.Config{APIEndpoint: "http://localhost:8080"}, "http://bot-a/messages", nil, nil, nil)
+
+		require.NoError(t, err)
+		require.IsType(t, &ForwardRepositoryImpl{}, sink)
+		assert.Equal(t, "http://bot-a/messages", sink.(*ForwardRepositoryImpl).config.APIEndpoint)
+	})
+
+	t.Run("invalid http headers configuration", func(t *testing.T) {
+		_, err := NewSink(config.Config{ForwardHeaders: []string{"[trace"}}, "", nil, nil, nil)
+
+		assert.ErrorContains(t, err, "invalid header pattern [trace")
+	})
+
+	t.Run("grpc", func(t *testing.T) {
+		sink, err := NewSink(config.Config{SinkType: "grpc", GRPCTarget: "localhost:50051", GRPCInsecure: true}, "", nil, nil, nil)
+
+		require.NoError(t, err)
+		require.IsType(t, &GRPCForwardRepository{}, sink)
+		assert.Equal(t, "localhost:50051", sink.(*GRPCForwardRepository).config.GRPCTarget)
+		assert.NoError(t, sink.(*GRPCForwardRepository).Close())
+	})
+
+	t.Run("invalid grpc TLS configuration", func(t *testing.T) {
+		_, err := NewSink(config.Config{SinkType: "grpc", HTTPTLSMinVersion: "1.0.1"}, "", nil, nil, nil)
+
+		assert.ErrorContains(t, err, "unsupported minimum TLS version")
+	})
+
+	t.Run("kafka", func(t *testing.T) {
+		sink, err := NewSink(config.Config{SinkType: "kafka", SinkKafkaBroker: "team-kafka:9092"}, "bridge.telegram", nil, nil, nil)
+
+		require.NoError(t, err)
+		require.IsType(t, &KafkaSink{}, sink)
+		assert.Equal(t, "bridge.telegram", sink.(*KafkaSink).topic)
+		sink.(*KafkaSink).producer.Close()
+	})
+
+	t.Run("unknown sink", func(t *testing.T) {
+		_, err := NewSink(config.Config{SinkType: "smtp"}, "", nil, nil, nil)
+
+		assert.EqualError(t, err, "unknown sink type: smtp")
+	})
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"anyker/config"
+	"anyker/internal/domain"
+	"fmt"
+)
+
+// NewSource creates the consumer of the source selected by the source type, Kafka if empty.
+func NewSource(cfg config.Config) (domain.ConsumerRepository, error) {
+	switch cfg.Source() {
+	case config.SourceKafka:
+		return NewConsumer(cfg)
+	case config.SourceAMQP:
+		return NewAMQPConsumer(cfg)
+	case config.SourceNATS:
+		return NewNATSConsumer(cfg)
+	case config.SourceRedis:
+		return NewRedisConsumer(cfg)
+	default:
+		return nil, fmt.Errorf("unknown source type: %s", cfg.SourceType)
+	}
+}
@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"anyker/config"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSetup_File(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(previous)
+
+	path := filepath.Join(t.TempDir(), "spans.json")
+	shutdown, err := Setup(context.Background(), config.Config{TracingExporter: "file", TracingFile: path, NanobotName: "nanobot-1"})
+	require.NoError(t, err)
+
+	_, span := otel.Tracer("test").Start(context.Background(), "forward orders")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	spans, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(spans), `"Name":"forward orders"`)
+	assert.Contains(t, string(spans), `"Value":"nanobot-1"`)
+}
+
+func TestSetup_None(t *testing.T) {
+	shutdown, err := Setup(context.Background(), config.Config{TracingExporter: "none"})
+	require.NoError(t, err)
+	defer shutdown(context.Background())
+
+	// the trace context is propagated even though spans are not recorded
+	traceparent := "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.MapCarrier{"traceparent": traceparent})
+	ctx, span := otel.Tracer("test").Start(ctx, "forward")
+	defer span.End()
+	assert.False(t, span.IsRecording())
+
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	assert.Equal(t, traceparent, carrier["traceparent"])
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", trace.SpanContextFromContext(ctx).TraceID().String())
+}
+
+func TestSetup_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      config.Config
+		expectedErr string
+	}{
+		{name: "unknown exporter", config: config.Config{TracingExporter: "zipkin"}, expectedErr: "unknown tracing exporter: zipkin"},
+		{name: "file without path", config: config.Config{TracingExporter: "file"}, expectedErr: "the file exporter requires a file"},
+		{
+			name:        "file in missing directory",
+			config:      config.Config{TracingExporter: "file", TracingFile: filepath.Join(t.TempDir(), "missing", "spans.json")},
+			expectedErr: "failed to open tracing file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Setup(context.Background(), tt.config)
+			assert.ErrorContains(t, err, tt.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+// Package metrics defines the Prometheus metrics of the consume and forward pipeline.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "anyker"
+
+var (
+	// MessagesConsumed counts the messages read from the source by topic.
+	MessagesConsumed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_consumed_total",
+		Help:      "Number of messages consumed from the source.",
+	}, []string{"topic"})
+
+	// MessagesForwarded counts the messages successfully forwarded by origin.
+	MessagesForwarded = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_forwarded_total",
+		Help:      "Number of messages successfully forwarded.",
+	}, []string{"origin"})
+
+	// MessagesFiltered counts the messages discarded by origin and reason (origin filter, expression filter or no route).
+	MessagesFiltered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_filtered_total",
+		Help:      "Number of messages discarded without being forwarded.",
+	}, []string{"origin", "reason"})
+
+	// ForwardFailures counts the messages that could not be forwarded, by last status code and error class.
+	ForwardFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "forward_failures_total",
+		Help:      "Number of messages that failed to be forwarded after all attempts.",
+	}, []string{"status_code", "error_class"})
+
+	// ForwardDuration observes the time spent forwarding a message, retries included.
+	ForwardDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "forward_duration_seconds",
+		Help:      "Time spent forwarding a message, retries included.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 14),
+	})
+
+	// EndToEndLag observes the time from the message timestamp in the source until the message is forwarded.
+	EndToEndLag = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "end_to_end_lag_seconds",
+		Help:      "Time from the message timestamp in the source until the message is forwarded.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 16),
+	})
+
+	// CircuitBreakerState is the state of the circuit breaker around the downstream service.
+	CircuitBreakerState = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "circuit_breaker_state",
+		Help:      "State of the circuit breaker: 0 closed, 1 open, 2 half-open.",
+	})
+
+	// ConsumerLag is the number of messages behind the end of each assigned partition.
+	ConsumerLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "consumer_lag",
+		Help:      "Number of messages between the last consumed message and the end of the partition.",
+	}, []string{"topic", "partition"})
+)
@@ -4,34 +4,138 @@ import (
 	"anyker/cmd"
 	"anyker/config"
 	"anyker/internal/application"
+	"anyker/internal/application/correlation"
+	"anyker/internal/application/filter"
+	"anyker/internal/domain"
 	"anyker/internal/infrastructure/client"
 	"anyker/internal/infrastructure/repository"
+	"anyker/internal/infrastructure/tracing"
+	"context"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"io"
 	"net/http"
+	"time"
 )
 
 func main() {
 	// Load configuration
 	cfg := config.Load()
 	log.Info().Str("nanobot_name", cfg.NanobotName).Msg("Starting nanobot")
+	// messages are logged with the logger of their context, the global one is used outside of a message
+	zerolog.DefaultContextLogger = &log.Logger
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid tracing configuration")
+	}
+	defer func() {
+		// flush the pending spans
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.Error().Err(err).Msg("failed to shut down tracing")
+		}
+	}()
 
 	// Create http forward client.
 	// It's a good practice to set a timeout for HTTP clients in production.
+	transport, err := client.NewTransport(cfg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid HTTP TLS configuration")
+	}
 	httpClient := &http.Client{
-		Timeout: cfg.HTTPClientTimeout,
+		Timeout:   cfg.HTTPClientTimeout,
+		Transport: transport,
+	}
+	authenticator, err := client.NewAuthenticator(cfg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid auth configuration")
 	}
-	forwardHttpClient := client.NewHttpClient(httpClient, "")
+	signer, err := client.NewSigner(cfg.SigningKeys)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid SIGNING_KEYS")
+	}
+	forwardHttpClient := client.NewHttpClient(httpClient, authenticator, signer)
 
 	// Create repositories
-	forwardRepository := repository.NewForwardRepository(cfg, forwardHttpClient)
-
-	consumerRepository, err := repository.NewConsumer(cfg)
+	consumerRepository, err := repository.NewSource(cfg)
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to create consumerRepository")
 	}
 
+	var replyRepository domain.ReplyRepository
+	if cfg.ReplyTopic != "" {
+		replyRepository, err = repository.NewReplyProducer(cfg)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to create replyRepository")
+		}
+		defer replyRepository.Close()
+	}
+
+	// the destination is an API endpoint, a gRPC target or a Kafka topic depending on the sink, the configured one if empty
+	var sinkClosers []io.Closer
+	defer func() {
+		for _, closer := range sinkClosers {
+			closer.Close()
+		}
+	}()
+	newForwardRepository := func(destination string) domain.ForwardRepository {
+		sink, err := repository.NewSink(cfg, destination, forwardHttpClient, authenticator, replyRepository)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to create forwardRepository")
+		}
+		if closer, ok := sink.(io.Closer); ok {
+			sinkClosers = append(sinkClosers, closer)
+		}
+		return sink
+	}
+	var forwardRepository domain.ForwardRepository
+	if len(cfg.Routes) > 0 {
+		// the configured destination is not used, only the routes
+		routes := make(map[string]domain.ForwardRepository, len(cfg.Routes))
+		for origin, endpoint := range cfg.Routes {
+			routes[origin] = newForwardRepository(endpoint)
+			log.Info().Msgf("messages from origin %s are forwarded to %s", origin, endpoint)
+		}
+		forwardRepository = application.NewRouter(routes)
+	} else {
+		forwardRepository = newForwardRepository("")
+	}
+	if cfg.CircuitBreakerFailureThreshold > 0 {
+		// consumption is paused while the circuit is open. The breaker wraps the router, so one failing route
+		// pauses them all: the partitions mix origins and the workers are shared, so a breaker per route would
+		// still block the others once its messages wait in every worker.
+		pauser, _ := consumerRepository.(domain.Pauser)
+		forwardRepository = application.NewCircuitBreaker(cfg, forwardRepository, pauser)
+	}
+
+	var deadLetterRepository domain.DeadLetterRepository
+	if cfg.DeadLetterTopic != "" {
+		deadLetterRepository, err = repository.NewDeadLetterProducer(cfg)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to create deadLetterRepository")
+		}
+	}
+
+	var messageFilter *filter.Filter
+	if cfg.FilterExpression != "" {
+		messageFilter, err = filter.Compile(cfg.FilterExpression)
+		if err != nil {
+			log.Fatal().Err(err).Msg("invalid FILTER_EXPRESSION")
+		}
+	}
+
 	// Create use case
-	messageService := application.NewMessageService(cfg, forwardRepository, consumerRepository)
+	messageService, err := application.NewMessageService(cfg, forwardRepository, consumerRepository, deadLetterRepository, messageFilter)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid ORIGIN or ORIGIN_EXCLUDE")
+	}
+
+	generateID, err := correlation.NewGenerator(cfg.CorrelationIDFormat)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid CORRELATION_ID_FORMAT")
+	}
 
-	cmd.Run(messageService)
+	cmd.Run(cfg, messageService, generateID)
 }